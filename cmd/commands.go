@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/health"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/notifier"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/pkg/env"
+	"go.uber.org/zap"
+)
+
+// runOnce syncs the records once, for cron jobs and scripts
+func runOnce(args []string) int {
+	flags, configFile := newFlagSet("once")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		zap.S().Error(err)
+		return exitConfig
+	}
+
+	upd, err := newUpdater(ctx, cfg)
+	if err != nil {
+		zap.S().Error(err)
+		return exitConfig
+	}
+
+	err = upd.Sync(ctx)
+	upd.Wait()
+	if err != nil {
+		zap.S().Errorf("Sync failed: %v", err)
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+// runListZones prints the zones managed with the configuration
+func runListZones(args []string) int {
+	flags, configFile := newFlagSet("list-zones")
+	asJSON := flags.Bool("json", false, "print the zones as JSON")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	dns, code := loadDNS(ctx, *configFile)
+	if dns == nil {
+		return code
+	}
+
+	if *asJSON {
+		return printJSON(dns.Zones)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME")
+	for _, zone := range dns.Zones {
+		fmt.Fprintf(w, "%s\t%s\n", zone.ID, zone.Name)
+	}
+
+	return flush(w)
+}
+
+// runListRecords prints the records managed with the configuration, by zone and name
+func runListRecords(args []string) int {
+	flags, configFile := newFlagSet("list-records")
+	asJSON := flags.Bool("json", false, "print the records as JSON")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	dns, code := loadDNS(ctx, *configFile)
+	if dns == nil {
+		return code
+	}
+
+	records := sortedRecords(dns.Records)
+	if *asJSON {
+		return printJSON(records)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ZONE\tNAME\tTYPE\tCONTENT\tPROXIED\tTTL\tID")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%d\t%s\n",
+			record.ZoneName, record.Name, record.Type, record.Content, record.Proxied, record.TTL, record.ID)
+	}
+
+	return flush(w)
+}
+
+// runValidateConfig checks the configuration, the ip providers and the notification templates
+// without contacting Cloudflare
+func runValidateConfig(args []string) int {
+	flags, configFile := newFlagSet("validate-config")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	cfg, err := config.New(*configFile)
+	if err == nil {
+		_, err = newResolvers(cfg)
+	}
+	if err == nil {
+		_, err = notifier.New(cfg)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return exitConfig
+	}
+
+	fmt.Fprintln(stdout, "Configuration is valid")
+	return exitOK
+}
+
+// runCurrentIP prints the current public ip of every enabled family
+func runCurrentIP(args []string) int {
+	flags, configFile := newFlagSet("current-ip")
+	asJSON := flags.Bool("json", false, "print the ips as JSON, by family")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		zap.S().Error(err)
+		return exitConfig
+	}
+
+	resolvers, err := newResolvers(cfg)
+	if err != nil {
+		zap.S().Error(err)
+		return exitConfig
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	code := exitOK
+	ips := make(map[string]string, len(resolvers))
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, resolver := range resolvers {
+		ip, err := resolver.Resolve(ctx)
+		if err != nil {
+			zap.S().Errorf("Error getting the current %s: %v", resolver.Family, err)
+			code = exitFailure
+			continue
+		}
+		ips[resolver.Family.String()] = ip
+		fmt.Fprintf(w, "%s\t%s\n", resolver.Family, ip)
+	}
+
+	if *asJSON {
+		if jsonCode := printJSON(ips); jsonCode != exitOK {
+			return jsonCode
+		}
+		return code
+	}
+
+	if flushCode := flush(w); flushCode != exitOK {
+		return flushCode
+	}
+
+	return code
+}
+
+// runHealthcheck checks the health of the instance running in the same container, for the Docker HEALTHCHECK
+func runHealthcheck(args []string) int {
+	flags, _ := newFlagSet("healthcheck")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	addr, err := env.GetEnv("HTTP_ADDR", true, "")
+	if err != nil {
+		zap.S().Error(err)
+		return exitConfig
+	}
+
+	if err = health.Check(context.Background(), addr); err != nil {
+		zap.S().Error(err)
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+// loadDNS loads the zones and records with the configuration. It returns nil
+// and the exit code of the command when it fails.
+func loadDNS(ctx context.Context, configFile string) (*dnsapi.CFDNS, int) {
+	cfg, err := config.New(configFile)
+	if err != nil {
+		zap.S().Error(err)
+		return nil, exitConfig
+	}
+
+	dns, err := dnsapi.New(ctx, cfg)
+	if err != nil {
+		zap.S().Error(err)
+		return nil, exitConfig
+	}
+
+	return dns, exitOK
+}
+
+// sortedRecords flattens the records of every zone, sorted by zone, name and type
+func sortedRecords(byZone map[string][]dnsapi.Record) (records []dnsapi.Record) {
+	for zoneName, zoneRecords := range byZone {
+		for _, record := range zoneRecords {
+			record.ZoneName = zoneName
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.ZoneName != b.ZoneName {
+			return a.ZoneName < b.ZoneName
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Type < b.Type
+	})
+
+	return records
+}
+
+// printJSON prints the value as indented JSON
+func printJSON(value interface{}) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		zap.S().Error(err)
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+// flush writes the table to the output
+func flush(w *tabwriter.Writer) int {
+	if err := w.Flush(); err != nil {
+		zap.S().Error(err)
+		return exitFailure
+	}
+
+	return exitOK
+}
@@ -1,18 +1,26 @@
 package dnsapi
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/pkg/utils"
 	"go.uber.org/zap"
 )
 
+// ErrPermissionDenied is returned when the credentials are not allowed to manage a zone
+var ErrPermissionDenied = errors.New("permission denied")
+
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -21,16 +29,27 @@ type CFDNS struct {
 	HTTPClient HTTPClient
 	Cfg        *config.Config
 	Records    map[string][]Record
-	Zones      []Zone
+	// Missing are the configured records that don't exist yet, by zone name.
+	// They're created with the current ip when CreateMissingRecords is enabled.
+	Missing map[string][]Record
+	// Unconfigured are the records created by the updater that are no longer configured, by
+	// zone name. They're deleted by DeleteUnconfigured when DeleteRemovedRecords is enabled.
+	Unconfigured map[string][]Record
+	Zones        []Zone
+	// unmatched are the warnings about the names matching no record, logged again only when they change
+	unmatched []string
 }
 
 type Record struct {
+	Comment  string `json:"comment,omitempty"`
 	Content  string `json:"content"`
 	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Type     string `json:"type"`
 	ZoneID   string `json:"zone_id"`
 	ZoneName string `json:"zone_name"`
+	TTL      int    `json:"ttl"`
+	Proxied  bool   `json:"proxied"`
 }
 
 type Zone struct {
@@ -48,29 +67,43 @@ type Message struct {
 	Code    int    `json:"code"`
 }
 
-// New creates a new Dns struct instance
-func New(cfg *config.Config) (dns *CFDNS, err error) {
+// New creates a new Dns struct instance, loading the zones and records within the API timeout
+func New(ctx context.Context, cfg *config.Config) (dns *CFDNS, err error) {
 	zap.S().Debug("Creating new Dns struct")
 	dns = &CFDNS{
 		Cfg: cfg,
 	}
 
-	dns.HTTPClient = http.DefaultClient
+	// the per-request timeout applies to every attempt of the retrying client
+	client := &http.Client{Timeout: cfg.APIRequestTimeout}
+	dns.HTTPClient = newRetryingClient(&instrumentedClient{HTTPClient: client})
+
+	ctx, cancel := dns.withTimeout(ctx)
+	defer cancel()
+
+	if cfg.APIToken != "" {
+		err = dns.verifyToken(ctx)
+		if err != nil {
+			return dns, err
+		}
+	}
 
 	if len(cfg.ZoneIDs) > 0 {
-		err = dns.checkZoneIDs()
+		err = dns.checkZoneIDs(ctx)
 		if err != nil {
 			return dns, err
 		}
-	} else {
-		err = dns.getZoneIDs()
+	}
+
+	if len(cfg.ZoneNames) > 0 {
+		err = dns.getZoneIDs(ctx, nil)
 		if err != nil {
 			return dns, err
 		}
 	}
 
 	dns.Records = make(map[string][]Record)
-	err = dns.getRecords()
+	_, err = dns.getRecords(ctx)
 	if err != nil {
 		return dns, err
 	}
@@ -78,12 +111,12 @@ func New(cfg *config.Config) (dns *CFDNS, err error) {
 	return dns, nil
 }
 
-// CheckZoneIDs checks if the zone ids are valid
-func (dns *CFDNS) checkZoneIDs() (err error) {
-	zap.S().Info("Getting zones info")
-	reqURL := "https://api.cloudflare.com/client/v4/zones/"
+// verifyToken checks that the API token is valid and active
+func (dns *CFDNS) verifyToken(ctx context.Context) (err error) {
+	zap.S().Info("Verifying API token")
+	reqURL := "https://api.cloudflare.com/client/v4/user/tokens/verify"
 
-	req, err := createCFRequest(http.MethodGet, reqURL, dns.Cfg.Email, dns.Cfg.AuthKey, nil)
+	req, err := dns.createCFRequest(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return err
 	}
@@ -96,8 +129,11 @@ func (dns *CFDNS) checkZoneIDs() (err error) {
 	type ResponseBody struct {
 		Errors   []Error   `json:"errors"`
 		Messages []Message `json:"messages"`
-		Result   []Zone    `json:"result"`
-		Success  bool      `json:"success"`
+		Result   struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"result"`
+		Success bool `json:"success"`
 	}
 
 	var resBody ResponseBody
@@ -108,14 +144,33 @@ func (dns *CFDNS) checkZoneIDs() (err error) {
 	res.Body.Close()
 
 	if !resBody.Success || res.StatusCode != http.StatusOK {
-		zap.S().Errorf("Error checking zone id, skipping. HTTP status code: %d. Response body: %v", res.StatusCode, resBody)
+		return fmt.Errorf("invalid API token. HTTP status code: %d. Response body: %v", res.StatusCode, resBody)
+	}
+
+	if resBody.Result.Status != "active" {
+		return fmt.Errorf("API token is %s, it must be active", resBody.Result.Status)
+	}
+
+	zap.S().Debugf("API token %s is active", resBody.Result.ID)
+
+	return nil
+}
+
+// CheckZoneIDs checks if the zone ids are valid
+func (dns *CFDNS) checkZoneIDs(ctx context.Context) (err error) {
+	zap.S().Info("Getting zones info")
+	reqURL := "https://api.cloudflare.com/client/v4/zones"
+
+	zones, err := listAll[Zone](ctx, dns, reqURL, 50)
+	if err != nil {
+		return fmt.Errorf("error listing zones: %w", err)
 	}
 
 	for _, zoneID := range dns.Cfg.ZoneIDs {
 		zap.S().Infof("Checking zone id %s", zoneID)
 		isValid := false
 
-		for _, zone := range resBody.Result {
+		for _, zone := range zones {
 			if zone.ID == zoneID {
 				dns.Zones = append(dns.Zones, zone)
 				isValid = true
@@ -135,47 +190,35 @@ func (dns *CFDNS) checkZoneIDs() (err error) {
 	return nil
 }
 
-// GetZoneIDs gets the zone id from the zone name
-func (dns *CFDNS) getZoneIDs() (err error) {
+// GetZoneIDs gets the zone id from the zone name. When a lookup fails, the zone with
+// the same name among the known ones is kept, so a refresh doesn't drop it. Without
+// a known zone to fall back on the error is returned.
+func (dns *CFDNS) getZoneIDs(ctx context.Context, known []Zone) (err error) {
 	for _, zoneName := range dns.Cfg.ZoneNames {
 		zap.S().Infof("Getting zone id for %s", zoneName)
-		reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones?name=%s", zoneName)
-
-		req, err := createCFRequest(http.MethodGet, reqURL, dns.Cfg.Email, dns.Cfg.AuthKey, nil)
-		if err != nil {
-			return err
-		}
-
-		res, err := dns.HTTPClient.Do(req)
-		if err != nil {
-			return err
-		}
+		reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones?name=%s", url.QueryEscape(zoneName))
 
-		type ResponseBody struct {
-			Errors   []Error   `json:"errors"`
-			Messages []Message `json:"messages"`
-			Result   []Zone    `json:"result"`
-			Success  bool      `json:"success"`
-		}
-
-		var resBody ResponseBody
-		err = unmarshalResponse(res.Body, &resBody)
+		zones, err := listAll[Zone](ctx, dns, reqURL, 50)
 		if err != nil {
-			return err
-		}
-		res.Body.Close()
-
-		if !resBody.Success || res.StatusCode != http.StatusOK {
-			zap.S().Errorf("Error getting zone id, skipping. HTTP status code: %d. Response body: %v", res.StatusCode, resBody)
+			if zone, ok := zoneNamed(known, zoneName); ok {
+				zap.S().Warnf("Error getting zone id, keeping %s. %v", zone.ID, err)
+				if !dns.hasZone(zone.ID) {
+					dns.Zones = append(dns.Zones, zone)
+				}
+				continue
+			}
+			return fmt.Errorf("error getting zone id for %s: %w", zoneName, err)
 		}
 
-		if len(resBody.Result) == 0 {
+		if len(zones) == 0 {
 			zap.S().Errorf("No zone found with name %s, skipping.", zoneName)
 		}
 
-		for _, zone := range resBody.Result {
+		for _, zone := range zones {
 			if strings.EqualFold(zone.Name, zoneName) {
-				dns.Zones = append(dns.Zones, zone)
+				if !dns.hasZone(zone.ID) {
+					dns.Zones = append(dns.Zones, zone)
+				}
 				break
 			}
 		}
@@ -188,143 +231,439 @@ func (dns *CFDNS) getZoneIDs() (err error) {
 	return nil
 }
 
-// getRecords gets all the records for the zone
-func (dns *CFDNS) getRecords() (err error) {
+// getRecords gets the records of every zone and merges them into the managed records,
+// returning the records that started or stopped being managed
+func (dns *CFDNS) getRecords(ctx context.Context) (changes *RecordChanges, err error) {
 	zap.S().Info("Getting records")
-	for _, zone := range dns.Zones {
-		reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zone.ID)
+	globalSelector := NewRecordSelector(dns.Cfg.RecordIDs, dns.Cfg.RecordNames)
+	recordTypes := dns.recordTypes()
+	var globalCandidates []Record
+	fetched := make(map[string]map[string]Record, len(dns.Zones))
+	missing := make(map[string][]Record)
+	unconfigured := make(map[string][]Record)
+	// the names about to be created are not reported as unmatched
+	creating := make(map[string]bool)
+	var unmatched []string
 
-		req, err := createCFRequest(http.MethodGet, reqURL, dns.Cfg.Email, dns.Cfg.AuthKey, nil)
-		if err != nil {
-			return err
+	for _, zone := range dns.Zones {
+		selector, zoneSpecific := dns.selectorFor(zone)
+		if !zoneSpecific {
+			selector = globalSelector
 		}
+		var candidates []Record
 
-		res, err := dns.HTTPClient.Do(req)
-		if err != nil {
-			return err
-		}
+		reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zone.ID)
 
-		type ResponseBody struct {
-			Errors   []Error   `json:"errors"`
-			Messages []Message `json:"messages"`
-			Result   []Record  `json:"result"`
-			Success  bool      `json:"success"`
+		records, err := listAll[Record](ctx, dns, reqURL, 100)
+		if err != nil {
+			var resErr *ResponseError
+			if errors.As(err, &resErr) {
+				if err := dns.permissionError(zone.Name, resErr.StatusCode, resErr.Errors); err != nil {
+					return nil, err
+				}
+			}
+			return nil, fmt.Errorf("error getting records for zone %s: %w", zone.Name, err)
 		}
 
-		var resBody ResponseBody
-		err = unmarshalResponse(res.Body, &resBody)
-		if err != nil {
-			return err
+		if len(records) == 0 && !dns.Cfg.CreateMissingRecords {
+			return nil, errors.New("no records found")
 		}
-		res.Body.Close()
 
-		if !resBody.Success || res.StatusCode != http.StatusOK {
-			strErr := fmt.Sprintf("Error getting records for zone %s. HTTP status code: %d. Response body: %v", zone.Name, res.StatusCode, resBody)
-			return errors.New(strErr)
+		recordsMap := make(map[string]Record)
+		for _, record := range records {
+			if !utils.StringInSlice(record.Type, recordTypes) {
+				continue
+			}
+			// the listing doesn't always carry the zone id, the updates and deletions need it
+			record.ZoneID = zone.ID
+			candidates = append(candidates, record)
+
+			if selector.Match(record) {
+				recordsMap[recordKey(record)] = record
+			} else if record.Comment == ManagedComment {
+				unconfigured[zone.Name] = append(unconfigured[zone.Name], record)
+			}
 		}
 
-		if len(resBody.Result) == 0 {
-			return errors.New("no records found")
+		if dns.Cfg.CreateMissingRecords {
+			if zoneMissing := dns.missingRecords(zone, selector, records); len(zoneMissing) > 0 {
+				missing[zone.Name] = zoneMissing
+				for _, record := range zoneMissing {
+					creating[record.Name] = true
+				}
+			}
 		}
 
-		recordsMap := make(map[string]Record)
-		for _, record := range resBody.Result {
-			if record.Type == "A" && (len(dns.Cfg.RecordIDs) == 0 || utils.StringInSlice(record.ID, dns.Cfg.RecordIDs)) {
-				recordsMap[record.Name] = record
+		if zoneSpecific {
+			for _, name := range selector.Unmatched(candidates) {
+				if !creating[name] {
+					unmatched = append(unmatched, fmt.Sprintf("Record name %s doesn't match any %s record in zone %s", name, strings.Join(recordTypes, "/"), zone.Name))
+				}
 			}
+		} else {
+			globalCandidates = append(globalCandidates, candidates...)
 		}
 
 		if len(recordsMap) == 0 {
-			zap.S().Errorf("No records found for zone %s", zone.Name)
+			if len(missing[zone.Name]) == 0 {
+				zap.S().Errorf("No records found for zone %s", zone.Name)
+			}
 			continue
 		}
+		fetched[zone.Name] = recordsMap
+	}
+
+	for _, name := range globalSelector.Unmatched(globalCandidates) {
+		if !creating[name] {
+			unmatched = append(unmatched, fmt.Sprintf("Record name %s doesn't match any %s record in the configured zones", name, strings.Join(recordTypes, "/")))
+		}
+	}
 
-		if _, ok := dns.Records[zone.Name]; !ok {
-			dns.Records[zone.Name] = make([]Record, 0)
+	if !utils.SameStrings(unmatched, dns.unmatched) {
+		for _, warning := range unmatched {
+			zap.S().Warn(warning)
 		}
+	}
+	dns.unmatched = unmatched
 
-		for i, record := range dns.Records[zone.Name] {
-			if updatedRecord, ok := recordsMap[record.Name]; ok {
-				dns.Records[zone.Name][i] = updatedRecord
-				delete(recordsMap, record.Name)
+	if len(fetched) == 0 && len(missing) == 0 {
+		return nil, errors.New("no records found")
+	}
+
+	changes = dns.mergeRecords(fetched)
+	dns.Missing = missing
+	dns.Unconfigured = unconfigured
+	if !dns.Cfg.DeleteRemovedRecords {
+		for zoneName, records := range unconfigured {
+			for _, record := range records {
+				zap.S().Warnf("Record %s in zone %s was created by the updater but is no longer configured, enable the deletion of the removed records to delete it", record.Name, zoneName)
 			}
 		}
+	}
 
-		for _, newRecord := range recordsMap {
-			dns.Records[zone.Name] = append(dns.Records[zone.Name], newRecord)
+	return changes, nil
+}
+
+// UpdateResult lists, by zone name, the records handled by UpdateRecords.
+// In dry run mode Updated holds the records as they would have been updated.
+// Created holds the missing records that were created, which are also in Updated.
+// Deleted holds the records found deleted, which are no longer managed.
+type UpdateResult struct {
+	Created   map[string][]Record
+	Updated   map[string][]Record
+	Unchanged map[string][]Record
+	Deleted   map[string][]Record
+	Failed    map[string][]RecordFailure
+	DryRun    bool
+}
+
+// RecordFailure describes a record that could not be updated
+type RecordFailure struct {
+	Err        error
+	Record     Record
+	Errors     []Error
+	StatusCode int
+}
+
+func (f RecordFailure) Error() string {
+	return fmt.Sprintf("error updating record %s: %v", f.Record.Name, f.Err)
+}
+
+func (f RecordFailure) Unwrap() error {
+	return f.Err
+}
+
+// newUpdateResult creates an empty UpdateResult
+func newUpdateResult() *UpdateResult {
+	return &UpdateResult{
+		Created:   make(map[string][]Record),
+		Updated:   make(map[string][]Record),
+		Unchanged: make(map[string][]Record),
+		Deleted:   make(map[string][]Record),
+		Failed:    make(map[string][]RecordFailure),
+	}
+}
+
+// FailedRecords returns the records that could not be updated, by zone name
+func (r *UpdateResult) FailedRecords() map[string][]Record {
+	records := make(map[string][]Record, len(r.Failed))
+	for zoneName, failures := range r.Failed {
+		for _, failure := range failures {
+			records[zoneName] = append(records[zoneName], failure.Record)
 		}
+	}
+
+	return records
+}
 
+// Err returns all the failures joined in a single error, or nil if every record was updated
+func (r *UpdateResult) Err() error {
+	var errs []error
+	for _, failures := range r.Failed {
+		for _, failure := range failures {
+			errs = append(errs, failure)
+		}
 	}
 
-	if len(dns.Records) == 0 {
-		return errors.New("no records found")
+	return errors.Join(errs...)
+}
+
+// RecordCount returns the number of records managed across all the zones
+func (dns *CFDNS) RecordCount() (count int) {
+	for _, records := range dns.Records {
+		count += len(records)
 	}
 
-	return nil
+	return count
 }
 
-// UpdateRecords updates the records with the current ip
-func (dns *CFDNS) UpdateRecords(currentIP string) (updatedRecords map[string][]string, err error) {
-	zap.S().Info("Checking records")
-	updatedRecords = make(map[string][]string)
+// RecordNames returns the names of the records, by zone name
+func RecordNames(records map[string][]Record) map[string][]string {
+	names := make(map[string][]string, len(records))
+	for zoneName, zoneRecords := range records {
+		for _, record := range zoneRecords {
+			names[zoneName] = append(names[zoneName], record.Name)
+		}
+	}
+
+	return names
+}
+
+// UpdateRecords updates the records matching the family of the current ip
+// (A for IPv4, AAAA for IPv6) with the current ip. Records already pointing
+// to the current ip are left untouched. Every record is attempted, even when
+// some of them fail: the returned error joins all the failures.
+func (dns *CFDNS) UpdateRecords(ctx context.Context, currentIP string) (result *UpdateResult, err error) {
+	return dns.updateRecords(ctx, currentIP, func(string, Record) bool { return true })
+}
+
+// RetryFailed updates again only the records that failed in a previous result
+func (dns *CFDNS) RetryFailed(ctx context.Context, currentIP string, previous *UpdateResult) (result *UpdateResult, err error) {
+	failedIDs := make(map[string]bool)
+	for _, failures := range previous.Failed {
+		for _, failure := range failures {
+			failedIDs[failure.Record.ID] = true
+		}
+	}
+
+	return dns.updateRecords(ctx, currentIP, func(_ string, record Record) bool {
+		return failedIDs[record.ID]
+	})
+}
+
+// updateRecords updates the records accepted by the filter with the current ip
+func (dns *CFDNS) updateRecords(ctx context.Context, currentIP string, filter func(zoneName string, record Record) bool) (result *UpdateResult, err error) {
+	ctx, cancel := dns.withTimeout(ctx)
+	defer cancel()
+
+	recordType := RecordTypeForIP(currentIP)
+	zap.S().Infof("Checking %s records", recordType)
+	result = newUpdateResult()
+	result.DryRun = dns.Cfg.DryRun
 
 	for zoneName, records := range dns.Records {
 		for i, record := range records {
-			zap.S().Infof("Updating record %s", record.Name)
-			reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", record.ZoneID, record.ID)
-
-			payload := strings.NewReader(fmt.Sprintf(`{"content":"%s"}`, currentIP))
-			req, err := createCFRequest(http.MethodPatch, reqURL, dns.Cfg.Email, dns.Cfg.AuthKey, payload)
-			if err != nil {
-				zap.S().Fatal(err)
-				return updatedRecords, err
+			if record.Type != recordType || !filter(zoneName, record) {
+				continue
 			}
 
-			res, err := dns.HTTPClient.Do(req)
-			if err != nil {
-				zap.S().Fatal(err)
-				return updatedRecords, err
+			options := dns.recordOptions(Zone{ID: record.ZoneID, Name: zoneName}, record)
+			plannedRecord := record
+			plannedRecord.Content = currentIP
+			if options.Proxied != nil {
+				plannedRecord.Proxied = *options.Proxied
+			}
+			if options.TTL != 0 {
+				plannedRecord.TTL = options.TTL
 			}
 
-			type ResponseBody struct {
-				Result   Record    `json:"result"`
-				Errors   []Error   `json:"errors"`
-				Messages []Message `json:"messages"`
-				Success  bool      `json:"success"`
+			if sameIP(record.Content, currentIP) && plannedRecord.Proxied == record.Proxied && plannedRecord.TTL == record.TTL {
+				zap.S().Debugf("Record %s already points to %s, skipping", record.Name, currentIP)
+				result.Unchanged[zoneName] = append(result.Unchanged[zoneName], record)
+				continue
 			}
 
-			var resBody ResponseBody
-			err = unmarshalResponse(res.Body, &resBody)
-			if err != nil {
-				zap.S().Fatal(err)
-				return updatedRecords, err
+			if dns.Cfg.DryRun {
+				zap.S().Infof("[DRY RUN] Would update %s record %s in zone %s from %s (proxied: %t, ttl: %d) to %s (proxied: %t, ttl: %d)",
+					record.Type, record.Name, zoneName, record.Content, record.Proxied, record.TTL, plannedRecord.Content, plannedRecord.Proxied, plannedRecord.TTL)
+				result.Updated[zoneName] = append(result.Updated[zoneName], plannedRecord)
+				continue
 			}
-			res.Body.Close()
-			zap.S().Debugf("Response body: %+v", resBody)
 
-			if !resBody.Success || res.StatusCode != http.StatusOK {
-				strErr := fmt.Sprintf("Error updating record %s. HTTP status code: %d. Response body: %v", record.Name, res.StatusCode, resBody)
-				return updatedRecords, errors.New(strErr)
+			zap.S().Infof("Updating record %s", record.Name)
+			updatedRecord, failure := dns.updateRecord(ctx, zoneName, record, currentIP, options)
+			if failure != nil {
+				if failure.StatusCode == http.StatusNotFound {
+					// not a failure of the updater, the record is simply gone
+					metrics.RecordUpdates.Inc("deleted")
+					zap.S().Warnf("Record %s was deleted, it's no longer managed", record.Name)
+					result.Deleted[zoneName] = append(result.Deleted[zoneName], record)
+					continue
+				}
+				metrics.RecordUpdates.Inc("failure")
+				zap.S().Error(failure)
+				result.Failed[zoneName] = append(result.Failed[zoneName], *failure)
+				continue
 			}
+			metrics.RecordUpdates.Inc("success")
 
-			records[i] = resBody.Result
+			records[i] = updatedRecord
+			result.Updated[zoneName] = append(result.Updated[zoneName], updatedRecord)
+		}
+	}
+
+	for zoneName, deleted := range result.Deleted {
+		dns.forgetRecords(zoneName, deleted)
+	}
+	dns.createRecords(ctx, recordType, currentIP, result)
 
-			updatedRecords[zoneName] = append(updatedRecords[zoneName], record.Name)
+	return result, result.Err()
+}
+
+// updateRecord sets the content of a single record to the current ip, along with the configured options
+func (dns *CFDNS) updateRecord(ctx context.Context, zoneName string, record Record, currentIP string, options config.RecordRule) (updatedRecord Record, failure *RecordFailure) {
+	failure = &RecordFailure{Record: record}
+	reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", record.ZoneID, record.ID)
+
+	fields := map[string]interface{}{"content": currentIP}
+	if options.Proxied != nil {
+		fields["proxied"] = *options.Proxied
+	}
+	if options.TTL != 0 {
+		fields["ttl"] = options.TTL
+	}
+
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	req, err := dns.createCFRequest(ctx, http.MethodPatch, reqURL, bytes.NewReader(payload))
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+	// the patch sets absolute values, so sending it twice has the same effect
+	req.Header[idempotencyKey] = nil
+
+	res, err := dns.HTTPClient.Do(req)
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	type ResponseBody struct {
+		Result   Record    `json:"result"`
+		Errors   []Error   `json:"errors"`
+		Messages []Message `json:"messages"`
+		Success  bool      `json:"success"`
+	}
+
+	var resBody ResponseBody
+	err = unmarshalResponse(res.Body, &resBody)
+	res.Body.Close()
+	failure.StatusCode = res.StatusCode
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+	zap.S().Debugf("Response body: %+v", resBody)
+	failure.Errors = resBody.Errors
+
+	if err := dns.permissionError(zoneName, res.StatusCode, resBody.Errors); err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	if !resBody.Success || res.StatusCode != http.StatusOK {
+		failure.Err = fmt.Errorf("HTTP status code: %d. Errors: %v", res.StatusCode, resBody.Errors)
+		return record, failure
+	}
+
+	return resBody.Result, nil
+}
+
+// permissionError returns a descriptive error when Cloudflare refused a DNS call
+// because the API token doesn't grant access to the zone, nil otherwise
+func (dns *CFDNS) permissionError(zoneName string, statusCode int, errs []Error) error {
+	denied := statusCode == http.StatusForbidden
+	for _, e := range errs {
+		if e.Code == 9109 || e.Code == 10000 {
+			denied = true
 		}
 	}
 
-	return updatedRecords, nil
+	if !denied {
+		return nil
+	}
+
+	if dns.Cfg.APIToken != "" {
+		return fmt.Errorf("%w: the API token lacks the Zone.DNS:Edit permission for zone %s. Response errors: %v", ErrPermissionDenied, zoneName, errs)
+	}
+
+	return fmt.Errorf("%w: access denied to zone %s. Response errors: %v", ErrPermissionDenied, zoneName, errs)
+}
+
+// recordTypes returns the record types managed according to the enabled ip families
+func (dns *CFDNS) recordTypes() (types []string) {
+	if dns.Cfg.IPv4Enabled {
+		types = append(types, "A")
+	}
+	if dns.Cfg.IPv6Enabled {
+		types = append(types, "AAAA")
+	}
+
+	return types
+}
+
+// RecordTypeForIP returns the record type holding the given ip address
+func RecordTypeForIP(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "AAAA"
+	}
+
+	return "A"
+}
+
+// sameIP reports whether the record content is the given ip address
+func sameIP(content, ip string) bool {
+	contentIP, currentIP := net.ParseIP(content), net.ParseIP(ip)
+	if contentIP != nil && currentIP != nil {
+		return contentIP.Equal(currentIP)
+	}
+
+	return content == ip
+}
+
+// recordKey identifies a record inside a zone, so A and AAAA records with the same name are kept apart
+func recordKey(record Record) string {
+	return record.Type + " " + record.Name
+}
+
+// withTimeout bounds an operation made of several API calls, like loading the records, with the API timeout
+func (dns *CFDNS) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if dns.Cfg.APITimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, dns.Cfg.APITimeout)
 }
 
-// createCFRequest creates an HTTP request with the cloudflare headers
-func createCFRequest(method, url, email, authKey string, body io.Reader) (req *http.Request, err error) {
-	req, err = http.NewRequest(method, url, body)
+// createCFRequest creates an HTTP request with the cloudflare headers.
+// An API token takes precedence over the Global API Key.
+func (dns *CFDNS) createCFRequest(ctx context.Context, method, url string, body io.Reader) (req *http.Request, err error) {
+	req, err = http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-Auth-Email", email)
-	req.Header.Set("X-Auth-Key", authKey)
+	if dns.Cfg.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+dns.Cfg.APIToken)
+	} else {
+		req.Header.Set("X-Auth-Email", dns.Cfg.Email)
+		req.Header.Set("X-Auth-Key", dns.Cfg.AuthKey)
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	return req, nil
@@ -2,9 +2,13 @@ package dnsapi
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
@@ -74,7 +78,7 @@ func TestDns_checkZoneIDs(t *testing.T) {
 				HTTPClient: mockClient,
 			}
 
-			err := dns.checkZoneIDs()
+			err := dns.checkZoneIDs(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("CheckZoneIDs() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -146,7 +150,7 @@ func TestDns_getZoneIDs(t *testing.T) {
 				HTTPClient: mockClient,
 			}
 
-			err := dns.getZoneIDs()
+			err := dns.getZoneIDs(context.Background(), nil)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("GetZoneIDs() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -168,10 +172,41 @@ func TestDns_getZoneIDs(t *testing.T) {
 	}
 }
 
+func TestDns_getZoneIDs_LookupError(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("name") == "example.org" {
+				return nil, errors.New("connection reset by peer")
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":[{"id":"testZoneID1","name":"example.com"}]}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken", ZoneNames: []string{"example.com", "example.org"}},
+		HTTPClient: mockClient,
+	}
+
+	err := dns.getZoneIDs(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "example.org") {
+		t.Fatalf("getZoneIDs() error = %v; want the lookup error of example.org", err)
+	}
+
+	// on refresh the known zone is kept instead
+	dns.Zones = nil
+	err = dns.getZoneIDs(context.Background(), []Zone{{ID: "testZoneID2", Name: "example.org"}})
+	if err != nil || len(dns.Zones) != 2 {
+		t.Errorf("getZoneIDs() = %v, %v; want example.com and the known example.org", dns.Zones, err)
+	}
+}
+
 func TestDns_getRecords(t *testing.T) {
 	tests := []struct {
 		name                string
 		recordIDs           []string
+		recordNames         []string
+		ipv6Enabled         bool
 		mockResponse        string
 		expectedRecordsMaps int
 		expectedRecordIDs   []string
@@ -202,6 +237,40 @@ func TestDns_getRecords(t *testing.T) {
 			expectedContents:    []string{"testContent"},
 			wantErr:             false,
 		},
+		{
+			name:                "IgnoresAAAAWhenIPv6Disabled",
+			recordIDs:           nil,
+			mockResponse:        `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID", "name": "testRecordName", "type": "A", "content": "testContent"}, {"id":"testRecordID6", "name": "testRecordName", "type": "AAAA", "content": "testContent6"}]}`,
+			expectedRecordsMaps: 1,
+			expectedRecordIDs:   []string{"testRecordID"},
+			expectedNames:       []string{"testRecordName"},
+			expectedTypes:       []string{"A"},
+			expectedContents:    []string{"testContent"},
+			wantErr:             false,
+		},
+		{
+			name:                "AAAAWithSameNameWhenIPv6Enabled",
+			recordIDs:           nil,
+			ipv6Enabled:         true,
+			mockResponse:        `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID6", "name": "testRecordName", "type": "AAAA", "content": "testContent6"}]}`,
+			expectedRecordsMaps: 1,
+			expectedRecordIDs:   []string{"testRecordID6"},
+			expectedNames:       []string{"testRecordName"},
+			expectedTypes:       []string{"AAAA"},
+			expectedContents:    []string{"testContent6"},
+			wantErr:             false,
+		},
+		{
+			name:                "WithRecordNamePattern",
+			recordNames:         []string{"*.testZoneName", "!nas.testZoneName"},
+			mockResponse:        `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID", "name": "home.testZoneName", "type": "A", "content": "testContent"}, {"id":"testRecordID2", "name": "nas.testZoneName", "type": "A", "content": "testContent"}, {"id":"testRecordID3", "name": "testZoneName", "type": "A", "content": "testContent"}]}`,
+			expectedRecordsMaps: 1,
+			expectedRecordIDs:   []string{"testRecordID"},
+			expectedNames:       []string{"home.testZoneName"},
+			expectedTypes:       []string{"A"},
+			expectedContents:    []string{"testContent"},
+			wantErr:             false,
+		},
 		{
 			name:                "InvalidRecordID",
 			recordIDs:           []string{"testRecordID"},
@@ -228,9 +297,12 @@ func TestDns_getRecords(t *testing.T) {
 			}
 
 			cfg := &config.Config{
-				AuthKey:   "testAuthKey",
-				Email:     "testEmail",
-				RecordIDs: tt.recordIDs,
+				AuthKey:     "testAuthKey",
+				Email:       "testEmail",
+				RecordIDs:   tt.recordIDs,
+				RecordNames: tt.recordNames,
+				IPv4Enabled: true,
+				IPv6Enabled: tt.ipv6Enabled,
 			}
 
 			dns := &CFDNS{
@@ -240,7 +312,7 @@ func TestDns_getRecords(t *testing.T) {
 				Zones:      []Zone{{ID: "testZoneID", Name: "testZoneName"}},
 			}
 
-			err := dns.getRecords()
+			_, err := dns.getRecords(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("GetRecords() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -278,13 +350,16 @@ func TestDns_getRecords(t *testing.T) {
 
 func TestDns_UpdateRecord(t *testing.T) {
 	tests := []struct {
-		name            string
-		initialRecords  map[string][]Record
-		updateIP        string
-		mockResponse    string
-		expectedRecords map[string][]string
-		updatedRecords  map[string][]Record
-		wantErr         bool
+		name              string
+		initialRecords    map[string][]Record
+		updateIP          string
+		mockResponse      string
+		expectedRecords   map[string][]string
+		expectedUnchanged map[string][]string
+		expectedFailed    map[string][]string
+		updatedRecords    map[string][]Record
+		expectedRequests  int
+		wantErr           bool
 	}{
 		{
 			name: "UpdateRecordSuccess",
@@ -313,7 +388,115 @@ func TestDns_UpdateRecord(t *testing.T) {
 					},
 				},
 			},
-			wantErr: false,
+			expectedRequests: 1,
+			wantErr:          false,
+		},
+		{
+			name: "UpdateOnlyMatchingFamily",
+			initialRecords: map[string][]Record{
+				"testZoneID": {
+					{
+						ID:      "testRecordID",
+						Name:    "testRecordName",
+						Type:    "A",
+						Content: "testIPOld",
+					},
+					{
+						ID:      "testRecordID6",
+						Name:    "testRecordName",
+						Type:    "AAAA",
+						Content: "2001:db8::1",
+					},
+				},
+			},
+			updateIP:     "2001:db8::2",
+			mockResponse: `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID6", "name": "testRecordName", "type": "AAAA", "content": "2001:db8::2"}}`,
+			expectedRecords: map[string][]string{
+				"testZoneID": {"testRecordName"},
+			},
+			updatedRecords: map[string][]Record{
+				"testZoneID": {
+					{
+						ID:      "testRecordID",
+						Name:    "testRecordName",
+						Type:    "A",
+						Content: "testIPOld",
+					},
+					{
+						ID:      "testRecordID6",
+						Name:    "testRecordName",
+						Type:    "AAAA",
+						Content: "2001:db8::2",
+					},
+				},
+			},
+			expectedRequests: 1,
+			wantErr:          false,
+		},
+		{
+			name: "SkipUnchangedRecords",
+			initialRecords: map[string][]Record{
+				"testZoneID": {
+					{
+						ID:      "testRecordID",
+						Name:    "testRecordName",
+						Type:    "A",
+						Content: "203.0.113.1",
+					},
+					{
+						ID:      "testRecordID2",
+						Name:    "testRecordName2",
+						Type:    "A",
+						Content: "203.0.113.2",
+					},
+				},
+			},
+			updateIP:     "203.0.113.1",
+			mockResponse: `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID2", "name": "testRecordName2", "type": "A", "content": "203.0.113.1"}}`,
+			expectedRecords: map[string][]string{
+				"testZoneID": {"testRecordName2"},
+			},
+			expectedUnchanged: map[string][]string{
+				"testZoneID": {"testRecordName"},
+			},
+			updatedRecords: map[string][]Record{
+				"testZoneID": {
+					{
+						ID:      "testRecordID",
+						Name:    "testRecordName",
+						Type:    "A",
+						Content: "203.0.113.1",
+					},
+					{
+						ID:      "testRecordID2",
+						Name:    "testRecordName2",
+						Type:    "A",
+						Content: "203.0.113.1",
+					},
+				},
+			},
+			expectedRequests: 1,
+			wantErr:          false,
+		},
+		{
+			name: "NothingToUpdate",
+			initialRecords: map[string][]Record{
+				"testZoneID": {
+					{
+						ID:      "testRecordID6",
+						Name:    "testRecordName",
+						Type:    "AAAA",
+						Content: "2001:0db8:0000::0001",
+					},
+				},
+			},
+			updateIP:        "2001:db8::1",
+			expectedRecords: map[string][]string{},
+			expectedUnchanged: map[string][]string{
+				"testZoneID": {"testRecordName"},
+			},
+			expectedRequests: 0,
+			wantErr:          false,
 		},
 		{
 			name: "UpdateRecordFail",
@@ -330,15 +513,21 @@ func TestDns_UpdateRecord(t *testing.T) {
 			updateIP:        "testIPNew",
 			mockResponse:    `{"success":false,"errors":[{"code":1004,"message":"DNS Validation Error","error_chain":[{"code":9003,"message":"Invalid IP","error_chain":[]}]}],"messages":[],"result":null}`,
 			expectedRecords: map[string][]string{},
-			updatedRecords:  map[string][]Record{},
-			wantErr:         true,
+			expectedFailed: map[string][]string{
+				"testZoneID": {"testRecordName"},
+			},
+			updatedRecords:   map[string][]Record{},
+			expectedRequests: 1,
+			wantErr:          true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
 			mockClient := &mocks.MockClient{
 				DoFunc: func(req *http.Request) (*http.Response, error) {
+					requests++
 					body := io.NopCloser(bytes.NewReader([]byte(tt.mockResponse)))
 					return &http.Response{
 						StatusCode: 200,
@@ -358,23 +547,19 @@ func TestDns_UpdateRecord(t *testing.T) {
 				Records:    tt.initialRecords,
 			}
 
-			updatedRecords, err := dns.UpdateRecords(tt.updateIP)
+			result, err := dns.UpdateRecords(context.Background(), tt.updateIP)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("UpdateRecords() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			for zoneID, updatedZoneRecords := range tt.expectedRecords {
-				if len(updatedRecords[zoneID]) != len(updatedZoneRecords) {
-					t.Fatalf("UpdateRecords() = %d; want %d", len(updatedRecords[zoneID]), len(updatedZoneRecords))
-				}
-
-				for i, expectedRecordName := range updatedZoneRecords {
-					if updatedRecords[zoneID][i] != expectedRecordName {
-						t.Errorf("UpdateRecords() = %s; want %s", updatedRecords[zoneID][i], expectedRecordName)
-					}
-				}
+			if requests != tt.expectedRequests {
+				t.Errorf("UpdateRecords() sent %d requests; want %d", requests, tt.expectedRequests)
 			}
 
+			assertRecordNames(t, "updated", RecordNames(result.Updated), tt.expectedRecords)
+			assertRecordNames(t, "unchanged", RecordNames(result.Unchanged), tt.expectedUnchanged)
+			assertRecordNames(t, "failed", RecordNames(result.FailedRecords()), tt.expectedFailed)
+
 			for zoneID, updatedZoneRecords := range tt.updatedRecords {
 				if len(dns.Records[zoneID]) != len(updatedZoneRecords) {
 					t.Fatalf("UpdateRecords() = %d; want %d", len(dns.Records[zoneID]), len(updatedZoneRecords))
@@ -389,3 +574,286 @@ func TestDns_UpdateRecord(t *testing.T) {
 		})
 	}
 }
+
+// assertRecordNames checks that the record names by zone match the expected ones
+func assertRecordNames(t *testing.T, kind string, names, expected map[string][]string) {
+	t.Helper()
+
+	if len(names) != len(expected) {
+		t.Fatalf("UpdateRecords() %s = %v; want %v", kind, names, expected)
+	}
+
+	for zoneID, expectedNames := range expected {
+		if len(names[zoneID]) != len(expectedNames) {
+			t.Fatalf("UpdateRecords() %s = %d; want %d", kind, len(names[zoneID]), len(expectedNames))
+		}
+
+		for i, expectedName := range expectedNames {
+			if names[zoneID][i] != expectedName {
+				t.Errorf("UpdateRecords() %s = %s; want %s", kind, names[zoneID][i], expectedName)
+			}
+		}
+	}
+}
+
+func TestDns_UpdateRecords_ContinueOnError(t *testing.T) {
+	responses := map[string]string{
+		"testRecordID1": `{"success":false,"errors":[{"code":1004,"message":"DNS Validation Error"}],"messages":[],"result":null}`,
+		"testRecordID2": `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID2", "name": "testRecordName2", "type": "A", "content": "testIPNew"}}`,
+	}
+	var requestedIDs []string
+	failFirst := true
+
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			recordID := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			requestedIDs = append(requestedIDs, recordID)
+
+			if recordID == "testRecordID1" && !failFirst {
+				body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1", "name": "testRecordName1", "type": "A", "content": "testIPNew"}}`
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}
+			if recordID == "testRecordID3" {
+				return nil, errors.New("connection reset")
+			}
+
+			statusCode := 200
+			if recordID == "testRecordID1" {
+				statusCode = 400
+			}
+			return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(responses[recordID]))}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{AuthKey: "testAuthKey", Email: "testEmail"},
+		HTTPClient: mockClient,
+		Records: map[string][]Record{
+			"testZoneName": {
+				{ID: "testRecordID1", Name: "testRecordName1", Type: "A", Content: "testIPOld"},
+				{ID: "testRecordID2", Name: "testRecordName2", Type: "A", Content: "testIPOld"},
+				{ID: "testRecordID3", Name: "testRecordName3", Type: "A", Content: "testIPOld"},
+			},
+		},
+	}
+
+	result, err := dns.UpdateRecords(context.Background(), "testIPNew")
+	if err == nil {
+		t.Fatal("UpdateRecords() error = nil; want an error")
+	}
+
+	if len(requestedIDs) != 3 {
+		t.Fatalf("UpdateRecords() sent %d requests; want 3", len(requestedIDs))
+	}
+
+	assertRecordNames(t, "updated", RecordNames(result.Updated), map[string][]string{"testZoneName": {"testRecordName2"}})
+	assertRecordNames(t, "failed", RecordNames(result.FailedRecords()), map[string][]string{"testZoneName": {"testRecordName1", "testRecordName3"}})
+
+	validationFailure := result.Failed["testZoneName"][0]
+	if validationFailure.StatusCode != 400 || len(validationFailure.Errors) != 1 || validationFailure.Errors[0].Code != 1004 {
+		t.Errorf("failure = %+v; want status 400 and code 1004", validationFailure)
+	}
+
+	// retrying only sends the records that failed
+	requestedIDs = nil
+	failFirst = false
+	result, err = dns.RetryFailed(context.Background(), "testIPNew", result)
+	if err == nil {
+		t.Fatal("RetryFailed() error = nil; want an error")
+	}
+
+	if strings.Join(requestedIDs, ",") != "testRecordID1,testRecordID3" {
+		t.Errorf("RetryFailed() requested %v; want [testRecordID1 testRecordID3]", requestedIDs)
+	}
+
+	assertRecordNames(t, "updated", RecordNames(result.Updated), map[string][]string{"testZoneName": {"testRecordName1"}})
+	assertRecordNames(t, "failed", RecordNames(result.FailedRecords()), map[string][]string{"testZoneName": {"testRecordName3"}})
+}
+
+func TestDns_UpdateRecords_DryRun(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			t.Errorf("unexpected %s request to %s in dry run", req.Method, req.URL)
+			return nil, errors.New("unexpected request")
+		},
+	}
+
+	initialRecord := Record{ID: "testRecordID", Name: "testRecordName", Type: "A", Content: "203.0.113.1"}
+	dns := &CFDNS{
+		Cfg:        &config.Config{AuthKey: "testAuthKey", Email: "testEmail", DryRun: true},
+		HTTPClient: mockClient,
+		Records:    map[string][]Record{"testZoneName": {initialRecord}},
+	}
+
+	result, err := dns.UpdateRecords(context.Background(), "203.0.113.2")
+	if err != nil {
+		t.Fatalf("UpdateRecords() error = %v", err)
+	}
+
+	if !result.DryRun {
+		t.Error("UpdateRecords() result is not marked as dry run")
+	}
+
+	if planned := result.Updated["testZoneName"]; len(planned) != 1 || planned[0].Content != "203.0.113.2" {
+		t.Errorf("UpdateRecords() planned = %v; want the record with content 203.0.113.2", planned)
+	}
+
+	if dns.Records["testZoneName"][0] != initialRecord {
+		t.Errorf("UpdateRecords() changed the record to %v in dry run", dns.Records["testZoneName"][0])
+	}
+}
+
+func TestDns_UpdateRecords_Timeout(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			// a hung connection, only the deadline of the request ends it
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken", APITimeout: 10 * time.Millisecond},
+		HTTPClient: mockClient,
+		Records: map[string][]Record{"testZoneName": {
+			{ID: "testRecordID1", Name: "testRecordName1", Type: "A", Content: "203.0.113.1"},
+			{ID: "testRecordID2", Name: "testRecordName2", Type: "A", Content: "203.0.113.1"},
+		}},
+	}
+
+	result, err := dns.UpdateRecords(context.Background(), "203.0.113.2")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("UpdateRecords() error = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	if failed := len(result.Failed["testZoneName"]); failed != 2 {
+		t.Errorf("UpdateRecords() failed = %d records; want 2", failed)
+	}
+}
+
+func TestDns_verifyToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockResponse string
+		statusCode   int
+		wantErr      bool
+	}{
+		{
+			name:         "ActiveToken",
+			mockResponse: `{"success":true,"errors":[],"messages":[{"code":10000,"message":"This API Token is valid and active"}],"result":{"id":"testTokenID","status":"active"}}`,
+			statusCode:   200,
+			wantErr:      false,
+		},
+		{
+			name:         "DisabledToken",
+			mockResponse: `{"success":true,"errors":[],"messages":[],"result":{"id":"testTokenID","status":"disabled"}}`,
+			statusCode:   200,
+			wantErr:      true,
+		},
+		{
+			name:         "InvalidToken",
+			mockResponse: `{"success":false,"errors":[{"code":1000,"message":"Invalid API Token"}],"messages":[],"result":null}`,
+			statusCode:   401,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mocks.MockClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					if req.URL.Path != "/client/v4/user/tokens/verify" {
+						t.Errorf("unexpected request to %s", req.URL.Path)
+					}
+					if auth := req.Header.Get("Authorization"); auth != "Bearer testToken" {
+						t.Errorf("Authorization header = %s; want Bearer testToken", auth)
+					}
+					body := io.NopCloser(bytes.NewReader([]byte(tt.mockResponse)))
+					return &http.Response{
+						StatusCode: tt.statusCode,
+						Body:       body,
+					}, nil
+				},
+			}
+
+			dns := &CFDNS{
+				Cfg:        &config.Config{APIToken: "testToken"},
+				HTTPClient: mockClient,
+			}
+
+			err := dns.verifyToken(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDns_createCFRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             *config.Config
+		expectedHeaders map[string]string
+	}{
+		{
+			name: "GlobalAPIKey",
+			cfg:  &config.Config{AuthKey: "testAuthKey", Email: "testEmail"},
+			expectedHeaders: map[string]string{
+				"X-Auth-Email":  "testEmail",
+				"X-Auth-Key":    "testAuthKey",
+				"Authorization": "",
+			},
+		},
+		{
+			name: "APIToken",
+			cfg:  &config.Config{APIToken: "testToken", AuthKey: "testAuthKey", Email: "testEmail"},
+			expectedHeaders: map[string]string{
+				"X-Auth-Email":  "",
+				"X-Auth-Key":    "",
+				"Authorization": "Bearer testToken",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dns := &CFDNS{Cfg: tt.cfg}
+
+			req, err := dns.createCFRequest(context.Background(), http.MethodGet, "https://api.cloudflare.com/client/v4/zones", nil)
+			if err != nil {
+				t.Fatalf("createCFRequest() error = %v", err)
+			}
+
+			for header, expected := range tt.expectedHeaders {
+				if value := req.Header.Get(header); value != expected {
+					t.Errorf("header %s = %s; want %s", header, value, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestDns_UpdateRecords_PermissionDenied(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			body := io.NopCloser(bytes.NewReader([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}],"messages":[],"result":null}`)))
+			return &http.Response{
+				StatusCode: 403,
+				Body:       body,
+			}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken"},
+		HTTPClient: mockClient,
+		Records: map[string][]Record{
+			"testZoneName": {{ID: "testRecordID", Name: "testRecordName", Type: "A", Content: "testIPOld"}},
+		},
+	}
+
+	_, err := dns.UpdateRecords(context.Background(), "testIPNew")
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("UpdateRecords() error = %v; want %v", err, ErrPermissionDenied)
+	}
+}
@@ -0,0 +1,283 @@
+package dnsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ManagedComment is the comment of the records created by the updater. Only the records
+// with this comment are deleted when they're removed from the configuration.
+const ManagedComment = "managed by cloudflare-dns-auto-updater"
+
+// missingRecords returns the records selected by exact name in the zone that don't exist yet,
+// one per managed record type, with the ttl and proxied options of their record rule
+func (dns *CFDNS) missingRecords(zone Zone, selector *RecordSelector, records []Record) (missing []Record) {
+	zoneName := normalizeName(zone.Name)
+
+	for _, name := range selector.Names() {
+		if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+			continue
+		}
+
+		existing := make(map[string]bool)
+		for _, record := range records {
+			if normalizeName(record.Name) == name {
+				existing[record.Type] = true
+			}
+		}
+		if existing["CNAME"] {
+			zap.S().Warnf("Record %s in zone %s is a CNAME, not creating address records with the same name", name, zone.Name)
+			continue
+		}
+
+		for _, recordType := range dns.recordTypes() {
+			if existing[recordType] {
+				continue
+			}
+
+			record := Record{Comment: ManagedComment, Name: name, Type: recordType, ZoneID: zone.ID, ZoneName: zone.Name, TTL: 1}
+			if !selector.Match(record) {
+				continue
+			}
+
+			options := dns.recordOptions(zone, record)
+			if options.Proxied != nil {
+				record.Proxied = *options.Proxied
+			}
+			if options.TTL != 0 {
+				record.TTL = options.TTL
+			}
+
+			zap.S().Infof("%s record %s doesn't exist in zone %s, it will be created", recordType, name, zone.Name)
+			missing = append(missing, record)
+		}
+	}
+
+	return missing
+}
+
+// HasMissing reports whether some records of the type are still to be created
+func (dns *CFDNS) HasMissing(recordType string) bool {
+	for _, records := range dns.Missing {
+		for _, record := range records {
+			if record.Type == recordType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// CreateMissing creates the missing records matching the family of the current ip, without
+// updating the existing ones
+func (dns *CFDNS) CreateMissing(ctx context.Context, currentIP string) (result *UpdateResult, err error) {
+	ctx, cancel := dns.withTimeout(ctx)
+	defer cancel()
+
+	result = newUpdateResult()
+	result.DryRun = dns.Cfg.DryRun
+	dns.createRecords(ctx, RecordTypeForIP(currentIP), currentIP, result)
+
+	return result, result.Err()
+}
+
+// createRecords creates the missing records of the record type with the current ip. The created
+// records become managed and are reported both as created and updated.
+func (dns *CFDNS) createRecords(ctx context.Context, recordType, currentIP string, result *UpdateResult) {
+	for zoneName, records := range dns.Missing {
+		kept := records[:0]
+
+		for _, record := range records {
+			if record.Type != recordType {
+				kept = append(kept, record)
+				continue
+			}
+
+			record.Content = currentIP
+			if dns.Cfg.DryRun {
+				zap.S().Infof("[DRY RUN] Would create %s record %s in zone %s with %s (proxied: %t, ttl: %d)",
+					record.Type, record.Name, zoneName, record.Content, record.Proxied, record.TTL)
+				result.Created[zoneName] = append(result.Created[zoneName], record)
+				result.Updated[zoneName] = append(result.Updated[zoneName], record)
+				kept = append(kept, record)
+				continue
+			}
+
+			zap.S().Infof("Creating %s record %s in zone %s", record.Type, record.Name, zoneName)
+			createdRecord, failure := dns.createRecord(ctx, zoneName, record)
+			if failure != nil {
+				metrics.RecordUpdates.Inc("failure")
+				zap.S().Error(failure)
+				result.Failed[zoneName] = append(result.Failed[zoneName], *failure)
+				kept = append(kept, record)
+				continue
+			}
+			metrics.RecordUpdates.Inc("success")
+
+			dns.Records[zoneName] = append(dns.Records[zoneName], createdRecord)
+			result.Created[zoneName] = append(result.Created[zoneName], createdRecord)
+			result.Updated[zoneName] = append(result.Updated[zoneName], createdRecord)
+		}
+
+		if len(kept) == 0 {
+			delete(dns.Missing, zoneName)
+		} else {
+			dns.Missing[zoneName] = kept
+		}
+	}
+}
+
+// createRecord creates a single record in its zone
+func (dns *CFDNS) createRecord(ctx context.Context, zoneName string, record Record) (createdRecord Record, failure *RecordFailure) {
+	failure = &RecordFailure{Record: record}
+	reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", record.ZoneID)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"comment": record.Comment,
+		"content": record.Content,
+		"name":    record.Name,
+		"proxied": record.Proxied,
+		"ttl":     record.TTL,
+		"type":    record.Type,
+	})
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	req, err := dns.createCFRequest(ctx, http.MethodPost, reqURL, bytes.NewReader(payload))
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	res, err := dns.HTTPClient.Do(req)
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	type ResponseBody struct {
+		Result   Record    `json:"result"`
+		Errors   []Error   `json:"errors"`
+		Messages []Message `json:"messages"`
+		Success  bool      `json:"success"`
+	}
+
+	var resBody ResponseBody
+	err = unmarshalResponse(res.Body, &resBody)
+	res.Body.Close()
+	failure.StatusCode = res.StatusCode
+	if err != nil {
+		failure.Err = err
+		return record, failure
+	}
+	zap.S().Debugf("Response body: %+v", resBody)
+	failure.Errors = resBody.Errors
+
+	if err := dns.permissionError(zoneName, res.StatusCode, resBody.Errors); err != nil {
+		failure.Err = err
+		return record, failure
+	}
+
+	if !resBody.Success || res.StatusCode != http.StatusOK {
+		failure.Err = fmt.Errorf("HTTP status code: %d. Errors: %v", res.StatusCode, resBody.Errors)
+		return record, failure
+	}
+
+	// keep the zone of the planned record, the response may not carry it
+	createdRecord = resBody.Result
+	createdRecord.ZoneID = record.ZoneID
+	return createdRecord, nil
+}
+
+// DeleteUnconfigured deletes the records created by the updater that are no longer configured,
+// when DeleteRemovedRecords is enabled, returning the deleted ones by zone name. The records
+// that failed to be deleted are attempted again next time. In dry run mode nothing is deleted.
+func (dns *CFDNS) DeleteUnconfigured(ctx context.Context) (deleted map[string][]Record) {
+	deleted = make(map[string][]Record)
+	if !dns.Cfg.DeleteRemovedRecords {
+		return deleted
+	}
+
+	ctx, cancel := dns.withTimeout(ctx)
+	defer cancel()
+
+	for zoneName, records := range dns.Unconfigured {
+		var kept []Record
+
+		for _, record := range records {
+			if dns.Cfg.DryRun {
+				zap.S().Infof("[DRY RUN] Would delete %s record %s in zone %s, it's no longer configured", record.Type, record.Name, zoneName)
+				continue
+			}
+
+			zap.S().Infof("Deleting %s record %s in zone %s, it's no longer configured", record.Type, record.Name, zoneName)
+			if err := dns.deleteRecord(ctx, zoneName, record); err != nil {
+				zap.S().Errorf("Error deleting record %s in zone %s: %v", record.Name, zoneName, err)
+				kept = append(kept, record)
+				continue
+			}
+			deleted[zoneName] = append(deleted[zoneName], record)
+		}
+
+		if len(kept) == 0 {
+			delete(dns.Unconfigured, zoneName)
+		} else {
+			dns.Unconfigured[zoneName] = kept
+		}
+	}
+
+	return deleted
+}
+
+// deleteRecord deletes a single record. A record already deleted is not an error.
+func (dns *CFDNS) deleteRecord(ctx context.Context, zoneName string, record Record) (err error) {
+	reqURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", record.ZoneID, record.ID)
+
+	req, err := dns.createCFRequest(ctx, http.MethodDelete, reqURL, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := dns.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	type ResponseBody struct {
+		Errors   []Error   `json:"errors"`
+		Messages []Message `json:"messages"`
+		Success  bool      `json:"success"`
+	}
+
+	var resBody ResponseBody
+	err = unmarshalResponse(res.Body, &resBody)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	zap.S().Debugf("Response body: %+v", resBody)
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if err := dns.permissionError(zoneName, res.StatusCode, resBody.Errors); err != nil {
+		return err
+	}
+
+	if !resBody.Success || res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status code: %d. Errors: %v", res.StatusCode, resBody.Errors)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package dnsapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+// createMockClient lists the records, creates the posted ones, updates and deletes any record,
+// keeping the created payloads and the deleted paths. Any request outside of testZoneID fails.
+func createMockClient(records string, created *[]map[string]interface{}, deleted *[]string) *mocks.MockClient {
+	return &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, "/client/v4/zones/testZoneID/") {
+				return nil, fmt.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":[` + records + `],"result_info":{"page":1,"per_page":100,"total_pages":1}}`
+
+			switch req.Method {
+			case http.MethodPost:
+				var payload map[string]interface{}
+				if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+					return nil, err
+				}
+				*created = append(*created, payload)
+				payload["id"] = "testCreatedID"
+				result, _ := json.Marshal(payload)
+				body = `{"success":true,"errors":[],"messages":[],"result":` + string(result) + `}`
+			case http.MethodPatch:
+				body = `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"}}`
+			case http.MethodDelete:
+				*deleted = append(*deleted, req.URL.Path)
+				body = `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID3"}}`
+			}
+
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+}
+
+func TestDns_CreateMissingRecords(t *testing.T) {
+	proxied := true
+	var created []map[string]interface{}
+	var deleted []string
+
+	dns := &CFDNS{
+		Cfg: &config.Config{
+			APIToken:             "testToken",
+			IPv4Enabled:          true,
+			CreateMissingRecords: true,
+			DeleteRemovedRecords: true,
+			Zones: []config.ZoneConfig{{ID: "testZoneID", Records: []config.RecordRule{
+				{Name: "home.example.com"},
+				{Name: "new.example.com", Proxied: &proxied, TTL: 300},
+				{Name: "www.example.com"},
+				{Name: "*.lab.example.com"},
+			}}},
+		},
+		HTTPClient: createMockClient(
+			`{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.1"},`+
+				`{"id":"testRecordID2","name":"www.example.com","type":"CNAME","content":"home.example.com"},`+
+				`{"id":"testRecordID3","name":"old.example.com","type":"A","content":"203.0.113.1","comment":"`+ManagedComment+`"},`+
+				`{"id":"testRecordID4","name":"other.example.com","type":"A","content":"203.0.113.1"}`,
+			&created, &deleted,
+		),
+		Records: make(map[string][]Record),
+		Zones:   []Zone{{ID: "testZoneID", Name: "example.com"}},
+	}
+
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("getRecords() error = %v", err)
+	}
+
+	assertRecordNames(t, "missing", RecordNames(dns.Missing), map[string][]string{"example.com": {"new.example.com"}})
+	assertRecordNames(t, "unconfigured", RecordNames(dns.Unconfigured), map[string][]string{"example.com": {"old.example.com"}})
+	if len(deleted) != 0 {
+		t.Fatalf("getRecords() deleted %v; want loading the records to change nothing", deleted)
+	}
+
+	removed := dns.DeleteUnconfigured(context.Background())
+	assertRecordNames(t, "deleted", RecordNames(removed), map[string][]string{"example.com": {"old.example.com"}})
+	if len(deleted) != 1 || !strings.HasSuffix(deleted[0], "/zones/testZoneID/dns_records/testRecordID3") {
+		t.Errorf("DeleteUnconfigured() deleted %v; want only the record created by the updater", deleted)
+	}
+	if len(dns.Unconfigured) != 0 {
+		t.Errorf("DeleteUnconfigured() left %v; want nothing left to delete", dns.Unconfigured)
+	}
+
+	result, err := dns.UpdateRecords(context.Background(), "203.0.113.2")
+	if err != nil {
+		t.Fatalf("UpdateRecords() error = %v", err)
+	}
+
+	if len(created) != 1 {
+		t.Fatalf("UpdateRecords() created %v; want new.example.com", created)
+	}
+	want := map[string]interface{}{
+		"comment": ManagedComment,
+		"content": "203.0.113.2",
+		"name":    "new.example.com",
+		"proxied": true,
+		"ttl":     float64(300),
+		"type":    "A",
+	}
+	for key, value := range want {
+		if created[0][key] != value {
+			t.Errorf("UpdateRecords() created %s = %v; want %v", key, created[0][key], value)
+		}
+	}
+
+	assertRecordNames(t, "created", RecordNames(result.Created), map[string][]string{"example.com": {"new.example.com"}})
+	assertRecordNames(t, "updated", RecordNames(result.Updated), map[string][]string{"example.com": {"home.example.com", "new.example.com"}})
+	assertRecordNames(t, "managed", RecordNames(dns.Records), map[string][]string{"example.com": {"home.example.com", "new.example.com"}})
+	if len(dns.Missing) != 0 || dns.HasMissing("A") {
+		t.Errorf("UpdateRecords() left missing records %v; want none", dns.Missing)
+	}
+}
+
+func TestDns_CreateMissingRecords_DryRun(t *testing.T) {
+	var created []map[string]interface{}
+	var deleted []string
+
+	dns := &CFDNS{
+		Cfg: &config.Config{
+			APIToken:             "testToken",
+			IPv4Enabled:          true,
+			DryRun:               true,
+			CreateMissingRecords: true,
+			DeleteRemovedRecords: true,
+			RecordNames:          []string{"new.example.com"},
+		},
+		HTTPClient: createMockClient(
+			`{"id":"testRecordID3","name":"old.example.com","type":"A","content":"203.0.113.1","comment":"`+ManagedComment+`"}`,
+			&created, &deleted,
+		),
+		Records: make(map[string][]Record),
+		Zones:   []Zone{{ID: "testZoneID", Name: "example.com"}},
+	}
+
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("getRecords() error = %v; want the missing record not to be an error", err)
+	}
+	removed := dns.DeleteUnconfigured(context.Background())
+
+	result, err := dns.CreateMissing(context.Background(), "203.0.113.2")
+	if err != nil {
+		t.Fatalf("CreateMissing() error = %v", err)
+	}
+
+	if len(created) != 0 || len(deleted) != 0 || len(removed) != 0 {
+		t.Errorf("dry run created %v and deleted %v; want no change", created, deleted)
+	}
+	assertRecordNames(t, "created", RecordNames(result.Created), map[string][]string{"example.com": {"new.example.com"}})
+	if !dns.HasMissing("A") {
+		t.Error("CreateMissing() dropped the missing record in dry run; want it kept")
+	}
+}
+
+func TestDns_getRecords_KeepsRemovedRecords(t *testing.T) {
+	var created []map[string]interface{}
+	var deleted []string
+
+	dns := &CFDNS{
+		Cfg: &config.Config{
+			APIToken:             "testToken",
+			IPv4Enabled:          true,
+			CreateMissingRecords: true,
+			RecordNames:          []string{"home.example.com"},
+		},
+		HTTPClient: createMockClient(
+			`{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.1"},`+
+				`{"id":"testRecordID3","name":"old.example.com","type":"A","content":"203.0.113.1","comment":"`+ManagedComment+`"}`,
+			&created, &deleted,
+		),
+		Records: make(map[string][]Record),
+		Zones:   []Zone{{ID: "testZoneID", Name: "example.com"}},
+	}
+
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("getRecords() error = %v", err)
+	}
+	removed := dns.DeleteUnconfigured(context.Background())
+
+	if len(deleted) != 0 || len(removed) != 0 || len(dns.Missing) != 0 {
+		t.Errorf("getRecords() deleted %v and planned %v; want nothing without the deletion enabled", deleted, dns.Missing)
+	}
+}
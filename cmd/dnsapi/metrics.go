@@ -0,0 +1,40 @@
+package dnsapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
+)
+
+// instrumentedClient is an HTTPClient counting the Cloudflare API requests by endpoint and status code
+type instrumentedClient struct {
+	HTTPClient HTTPClient
+}
+
+// Do sends the request and counts its outcome
+func (c *instrumentedClient) Do(req *http.Request) (*http.Response, error) {
+	res, err := c.HTTPClient.Do(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+	metrics.APIRequests.Inc(endpoint(req.URL.Path), req.Method, code)
+
+	return res, err
+}
+
+// endpoint returns the path of the API endpoint with the zone and record ids replaced
+// by placeholders, so the number of label values stays bounded
+func endpoint(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/client/v4"), "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i] != "" && (segments[i-1] == "zones" || segments[i-1] == "dns_records") {
+			segments[i] = ":id"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,110 @@
+package dnsapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/client/v4/user/tokens/verify", expected: "/user/tokens/verify"},
+		{path: "/client/v4/zones", expected: "/zones"},
+		{path: "/client/v4/zones/372e67954025e0ba6aaa6d586b9e0b59", expected: "/zones/:id"},
+		{path: "/client/v4/zones/372e67954025e0ba6aaa6d586b9e0b59/dns_records", expected: "/zones/:id/dns_records"},
+		{path: "/client/v4/zones/372e67954025e0ba6aaa6d586b9e0b59/dns_records/testRecordID", expected: "/zones/:id/dns_records/:id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := endpoint(tt.path); got != tt.expected {
+				t.Errorf("endpoint() = %s; want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInstrumentedClient(t *testing.T) {
+	fail := false
+	client := &instrumentedClient{
+		HTTPClient: &mocks.MockClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				if fail {
+					return nil, errors.New("connection refused")
+				}
+				return &http.Response{StatusCode: http.StatusForbidden, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+			},
+		},
+	}
+
+	const url = "https://api.cloudflare.com/client/v4/zones/testZoneID/dns_records/testRecordID"
+	forbidden := metrics.APIRequests.Value("/zones/:id/dns_records/:id", http.MethodPatch, "403")
+	errored := metrics.APIRequests.Value("/zones/:id/dns_records/:id", http.MethodPatch, "error")
+
+	req, _ := http.NewRequest(http.MethodPatch, url, nil)
+	if _, err := client.Do(req); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	fail = true
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("Do() error = nil; want the client error")
+	}
+
+	if got := metrics.APIRequests.Value("/zones/:id/dns_records/:id", http.MethodPatch, "403"); got != forbidden+1 {
+		t.Errorf("403 requests = %v; want %v", got, forbidden+1)
+	}
+	if got := metrics.APIRequests.Value("/zones/:id/dns_records/:id", http.MethodPatch, "error"); got != errored+1 {
+		t.Errorf("failed requests = %v; want %v", got, errored+1)
+	}
+}
+
+func TestRecordUpdates_Deleted(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, "/testRecordID2") {
+				body := `{"success":false,"errors":[{"code":81044,"message":"Record does not exist."}],"messages":[],"result":null}`
+				return &http.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken"},
+		HTTPClient: mockClient,
+		Records: map[string][]Record{"example.com": {
+			{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"},
+			{ID: "testRecordID2", Name: "nas.example.com", Type: "A", Content: "203.0.113.1"},
+		}},
+	}
+
+	success := metrics.RecordUpdates.Value("success")
+	failure := metrics.RecordUpdates.Value("failure")
+	deleted := metrics.RecordUpdates.Value("deleted")
+
+	if _, err := dns.UpdateRecords(context.Background(), "203.0.113.2"); err != nil {
+		t.Fatalf("UpdateRecords() error = %v", err)
+	}
+
+	if got := metrics.RecordUpdates.Value("success"); got != success+1 {
+		t.Errorf("successful updates = %v; want %v", got, success+1)
+	}
+	if got := metrics.RecordUpdates.Value("failure"); got != failure {
+		t.Errorf("failed updates = %v; want %v, a deleted record isn't a failure", got, failure)
+	}
+	if got := metrics.RecordUpdates.Value("deleted"); got != deleted+1 {
+		t.Errorf("deleted records = %v; want %v", got, deleted+1)
+	}
+}
@@ -0,0 +1,86 @@
+package dnsapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// ResultInfo is the pagination info of a Cloudflare list response
+type ResultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+}
+
+// ResponseError is returned when Cloudflare answers a request with an error
+type ResponseError struct {
+	Errors     []Error
+	Messages   []Message
+	StatusCode int
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("HTTP status code: %d. Errors: %v. Messages: %v", e.StatusCode, e.Errors, e.Messages)
+}
+
+// listAll walks every page of a Cloudflare list endpoint and returns the results of all of them
+func listAll[T any](ctx context.Context, dns *CFDNS, reqURL string, perPage int) (results []T, err error) {
+	pageURL, err := url.Parse(reqURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for page := 1; ; page++ {
+		query := pageURL.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(perPage))
+		pageURL.RawQuery = query.Encode()
+
+		req, err := dns.createCFRequest(ctx, http.MethodGet, pageURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := dns.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		type ResponseBody struct {
+			Errors     []Error    `json:"errors"`
+			Messages   []Message  `json:"messages"`
+			Result     []T        `json:"result"`
+			ResultInfo ResultInfo `json:"result_info"`
+			Success    bool       `json:"success"`
+		}
+
+		var resBody ResponseBody
+		err = unmarshalResponse(res.Body, &resBody)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if !resBody.Success || res.StatusCode != http.StatusOK {
+			return nil, &ResponseError{
+				Errors:     resBody.Errors,
+				Messages:   resBody.Messages,
+				StatusCode: res.StatusCode,
+			}
+		}
+
+		results = append(results, resBody.Result...)
+
+		if page >= resBody.ResultInfo.TotalPages || len(resBody.Result) == 0 {
+			return results, nil
+		}
+		zap.S().Debugf("Fetched page %d of %d from %s", page, resBody.ResultInfo.TotalPages, reqURL)
+	}
+}
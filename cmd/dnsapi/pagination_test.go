@@ -0,0 +1,145 @@
+package dnsapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+// newPagedMockClient returns a mock client serving the given result pages, selected by the page query parameter
+func newPagedMockClient(t *testing.T, pages []string, requestedPages *[]string) *mocks.MockClient {
+	return &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			page := req.URL.Query().Get("page")
+			*requestedPages = append(*requestedPages, page)
+
+			var index int
+			if _, err := fmt.Sscanf(page, "%d", &index); err != nil || index < 1 || index > len(pages) {
+				t.Fatalf("unexpected page %q requested", page)
+			}
+
+			body := fmt.Sprintf(`{"success":true,"errors":[],"messages":[],"result":[%s],"result_info":{"page":%d,"per_page":2,"total_pages":%d}}`, pages[index-1], index, len(pages))
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+			}, nil
+		},
+	}
+}
+
+func TestListAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		pages         []string
+		expectedIDs   []string
+		expectedPages []string
+	}{
+		{
+			name:          "SinglePage",
+			pages:         []string{`{"id":"testZoneID1","name":"testZoneName1"}`},
+			expectedIDs:   []string{"testZoneID1"},
+			expectedPages: []string{"1"},
+		},
+		{
+			name: "MultiplePages",
+			pages: []string{
+				`{"id":"testZoneID1","name":"testZoneName1"},{"id":"testZoneID2","name":"testZoneName2"}`,
+				`{"id":"testZoneID3","name":"testZoneName3"},{"id":"testZoneID4","name":"testZoneName4"}`,
+				`{"id":"testZoneID5","name":"testZoneName5"}`,
+			},
+			expectedIDs:   []string{"testZoneID1", "testZoneID2", "testZoneID3", "testZoneID4", "testZoneID5"},
+			expectedPages: []string{"1", "2", "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestedPages []string
+			dns := &CFDNS{
+				Cfg:        &config.Config{AuthKey: "testAuthKey", Email: "testEmail"},
+				HTTPClient: newPagedMockClient(t, tt.pages, &requestedPages),
+			}
+
+			zones, err := listAll[Zone](context.Background(), dns, "https://api.cloudflare.com/client/v4/zones?name=test", 2)
+			if err != nil {
+				t.Fatalf("listAll() error = %v", err)
+			}
+
+			if len(zones) != len(tt.expectedIDs) {
+				t.Fatalf("listAll() = %d results; want %d", len(zones), len(tt.expectedIDs))
+			}
+
+			for i, zone := range zones {
+				if zone.ID != tt.expectedIDs[i] {
+					t.Errorf("listAll() = %s; want %s", zone.ID, tt.expectedIDs[i])
+				}
+			}
+
+			if fmt.Sprint(requestedPages) != fmt.Sprint(tt.expectedPages) {
+				t.Errorf("requested pages = %v; want %v", requestedPages, tt.expectedPages)
+			}
+		})
+	}
+}
+
+func TestListAll_Error(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			body := io.NopCloser(bytes.NewReader([]byte(`{"success":false,"errors":[{"code":9109,"message":"Unauthorized to access requested resource"}],"messages":[],"result":null}`)))
+			return &http.Response{
+				StatusCode: 403,
+				Body:       body,
+			}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{AuthKey: "testAuthKey", Email: "testEmail"},
+		HTTPClient: mockClient,
+	}
+
+	_, err := listAll[Zone](context.Background(), dns, "https://api.cloudflare.com/client/v4/zones", 50)
+
+	var resErr *ResponseError
+	if !errors.As(err, &resErr) {
+		t.Fatalf("listAll() error = %v; want a ResponseError", err)
+	}
+
+	if resErr.StatusCode != 403 || len(resErr.Errors) != 1 || resErr.Errors[0].Code != 9109 {
+		t.Errorf("listAll() error = %+v; want status 403 and code 9109", resErr)
+	}
+}
+
+func TestDns_getRecords_Paginated(t *testing.T) {
+	var requestedPages []string
+	pages := []string{
+		`{"id":"testRecordID1","name":"a.testZoneName","type":"A","content":"testContent"},{"id":"testRecordID2","name":"b.testZoneName","type":"CNAME","content":"a.testZoneName"}`,
+		`{"id":"testRecordID3","name":"c.testZoneName","type":"A","content":"testContent"}`,
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{AuthKey: "testAuthKey", Email: "testEmail", IPv4Enabled: true},
+		HTTPClient: newPagedMockClient(t, pages, &requestedPages),
+		Records:    make(map[string][]Record),
+		Zones:      []Zone{{ID: "testZoneID", Name: "testZoneName"}},
+	}
+
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+
+	if len(dns.Records["testZoneName"]) != 2 {
+		t.Fatalf("GetRecords() = %d records; want 2", len(dns.Records["testZoneName"]))
+	}
+
+	if len(requestedPages) != 2 {
+		t.Errorf("requested pages = %v; want 2 pages", requestedPages)
+	}
+}
@@ -0,0 +1,123 @@
+package dnsapi
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// RecordChanges lists, by zone name, the records that started or stopped being managed
+type RecordChanges struct {
+	Added   map[string][]Record
+	Removed map[string][]Record
+}
+
+// newRecordChanges creates an empty RecordChanges
+func newRecordChanges() *RecordChanges {
+	return &RecordChanges{
+		Added:   make(map[string][]Record),
+		Removed: make(map[string][]Record),
+	}
+}
+
+// Empty reports whether no record was added or removed
+func (c *RecordChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// Refresh discovers the zones and records again, so the records created after the start are managed
+// and the deleted ones are forgotten. On error the managed zones and records are left untouched.
+func (dns *CFDNS) Refresh(ctx context.Context) (changes *RecordChanges, err error) {
+	ctx, cancel := dns.withTimeout(ctx)
+	defer cancel()
+
+	zap.S().Info("Refreshing zones and records")
+	known := dns.Zones
+	dns.Zones = nil
+
+	if len(dns.Cfg.ZoneIDs) > 0 {
+		err = dns.checkZoneIDs(ctx)
+	}
+	if err == nil && len(dns.Cfg.ZoneNames) > 0 {
+		err = dns.getZoneIDs(ctx, known)
+	}
+	if err == nil {
+		changes, err = dns.getRecords(ctx)
+	}
+
+	if err != nil {
+		dns.Zones = known
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// mergeRecords replaces the managed records with the fetched ones, by zone name and record key.
+// The records keep their order, the new ones are appended, and the ones missing are removed.
+func (dns *CFDNS) mergeRecords(fetched map[string]map[string]Record) *RecordChanges {
+	changes := newRecordChanges()
+
+	for zoneName, records := range dns.Records {
+		recordsMap := fetched[zoneName]
+		kept := make([]Record, 0, len(records))
+
+		for _, record := range records {
+			updatedRecord, ok := recordsMap[recordKey(record)]
+			if !ok {
+				changes.Removed[zoneName] = append(changes.Removed[zoneName], record)
+				continue
+			}
+			kept = append(kept, updatedRecord)
+			delete(recordsMap, recordKey(record))
+		}
+
+		if len(kept) == 0 {
+			delete(dns.Records, zoneName)
+		} else {
+			dns.Records[zoneName] = kept
+		}
+	}
+
+	for zoneName, recordsMap := range fetched {
+		for _, newRecord := range recordsMap {
+			dns.Records[zoneName] = append(dns.Records[zoneName], newRecord)
+			changes.Added[zoneName] = append(changes.Added[zoneName], newRecord)
+		}
+	}
+
+	return changes
+}
+
+// forgetRecords stops managing the records, e.g. because they were deleted
+func (dns *CFDNS) forgetRecords(zoneName string, forgotten []Record) {
+	ids := make(map[string]bool, len(forgotten))
+	for _, record := range forgotten {
+		ids[record.ID] = true
+	}
+
+	kept := dns.Records[zoneName][:0]
+	for _, record := range dns.Records[zoneName] {
+		if !ids[record.ID] {
+			kept = append(kept, record)
+		}
+	}
+
+	if len(kept) == 0 {
+		delete(dns.Records, zoneName)
+	} else {
+		dns.Records[zoneName] = kept
+	}
+}
+
+// zoneNamed returns the zone with the name among the zones
+func zoneNamed(zones []Zone, name string) (Zone, bool) {
+	for _, zone := range zones {
+		if strings.EqualFold(zone.Name, name) {
+			return zone, true
+		}
+	}
+
+	return Zone{}, false
+}
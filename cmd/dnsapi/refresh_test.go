@@ -0,0 +1,136 @@
+package dnsapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+// refreshMockClient serves the zones and, for every zone id, the records as the result of a list request
+func refreshMockClient(zones string, records map[string]string) *mocks.MockClient {
+	return &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			result, statusCode := zones, 200
+			if strings.HasSuffix(req.URL.Path, "/dns_records") {
+				zoneID := strings.Split(req.URL.Path, "/")[4]
+				var ok bool
+				if result, ok = records[zoneID]; !ok {
+					statusCode = 500
+				}
+			}
+
+			body := `{"success":true,"errors":[],"messages":[],"result":[` + result + `],"result_info":{"page":1,"per_page":100,"total_pages":1}}`
+			if statusCode != 200 {
+				body = `{"success":false,"errors":[{"code":10001,"message":"Internal error"}],"messages":[],"result":null}`
+			}
+			return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+}
+
+func TestDns_Refresh(t *testing.T) {
+	dns := &CFDNS{
+		Cfg: &config.Config{APIToken: "testToken", IPv4Enabled: true, ZoneIDs: []string{"testZoneID1", "testZoneID2"}},
+		HTTPClient: refreshMockClient(
+			`{"id":"testZoneID1","name":"example.com"},{"id":"testZoneID2","name":"example.org"}`,
+			map[string]string{
+				"testZoneID1": `{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"},` +
+					`{"id":"testRecordID3","name":"vpn.example.com","type":"A","content":"203.0.113.1"}`,
+				"testZoneID2": `{"id":"testRecordID4","name":"home.example.org","type":"A","content":"203.0.113.1"}`,
+			},
+		),
+		Records: map[string][]Record{
+			"example.com": {
+				{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"},
+				{ID: "testRecordID2", Name: "nas.example.com", Type: "A", Content: "203.0.113.1"},
+			},
+		},
+		Zones: []Zone{{ID: "testZoneID1", Name: "example.com"}},
+	}
+
+	changes, err := dns.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if len(dns.Zones) != 2 {
+		t.Errorf("Refresh() zones = %v; want example.com and example.org", dns.Zones)
+	}
+
+	assertRecordNames(t, "added", RecordNames(changes.Added), map[string][]string{
+		"example.com": {"vpn.example.com"},
+		"example.org": {"home.example.org"},
+	})
+	assertRecordNames(t, "removed", RecordNames(changes.Removed), map[string][]string{"example.com": {"nas.example.com"}})
+	assertRecordNames(t, "managed", RecordNames(dns.Records), map[string][]string{
+		"example.com": {"home.example.com", "vpn.example.com"},
+		"example.org": {"home.example.org"},
+	})
+
+	if content := dns.Records["example.com"][0].Content; content != "203.0.113.2" {
+		t.Errorf("Refresh() record content = %s; want the current one 203.0.113.2", content)
+	}
+
+	// nothing changed since the last refresh
+	changes, err = dns.Refresh(context.Background())
+	if err != nil || !changes.Empty() {
+		t.Errorf("Refresh() = %+v, %v; want no changes", changes, err)
+	}
+}
+
+func TestDns_Refresh_Error(t *testing.T) {
+	records := map[string][]Record{
+		"example.com": {{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"}},
+	}
+	zones := []Zone{{ID: "testZoneID1", Name: "example.com"}}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken", IPv4Enabled: true, ZoneIDs: []string{"testZoneID1"}},
+		HTTPClient: refreshMockClient(`{"id":"testZoneID1","name":"example.com"}`, map[string]string{}),
+		Records:    records,
+		Zones:      zones,
+	}
+
+	if _, err := dns.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() error = nil; want the error listing the records")
+	}
+
+	if len(dns.Zones) != 1 || len(dns.Records["example.com"]) != 1 {
+		t.Errorf("Refresh() left zones %v and records %v; want them untouched", dns.Zones, dns.Records)
+	}
+}
+
+func TestDns_UpdateRecords_Deleted(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, "/testRecordID2") {
+				body := `{"success":false,"errors":[{"code":81044,"message":"Record does not exist."}],"messages":[],"result":null}`
+				return &http.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken"},
+		HTTPClient: mockClient,
+		Records: map[string][]Record{"example.com": {
+			{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"},
+			{ID: "testRecordID2", Name: "nas.example.com", Type: "A", Content: "203.0.113.1"},
+		}},
+	}
+
+	result, err := dns.UpdateRecords(context.Background(), "203.0.113.2")
+	if err != nil {
+		t.Fatalf("UpdateRecords() error = %v; want the deleted record not to be a failure", err)
+	}
+
+	assertRecordNames(t, "deleted", RecordNames(result.Deleted), map[string][]string{"example.com": {"nas.example.com"}})
+	assertRecordNames(t, "managed", RecordNames(dns.Records), map[string][]string{"example.com": {"home.example.com"}})
+}
@@ -0,0 +1,180 @@
+package dnsapi
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// idempotencyKey marks a request as safe to replay, following the net/http convention:
+// with a nil value the header is recognized but never sent
+const idempotencyKey = "Idempotency-Key"
+
+// retryingClient is an HTTPClient retrying the requests rejected by the rate limit, and the idempotent
+// ones failing with a network error or a 5xx, backing off exponentially with jitter between the attempts
+type retryingClient struct {
+	HTTPClient HTTPClient
+	// Rand returns a number in [0.0,1.0), to spread the delays
+	Rand func() float64
+	// Sleep waits for the delay, or until the context is done
+	Sleep func(ctx context.Context, d time.Duration) error
+	// BaseDelay is the delay before the first retry, doubled at every attempt up to MaxDelay.
+	// A Retry-After longer than MaxDelay isn't honoured, the response is returned instead.
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+}
+
+// newRetryingClient creates a retryingClient with the default policy: 4 attempts,
+// waiting from 1 second up to 30 seconds
+func newRetryingClient(client HTTPClient) *retryingClient {
+	return &retryingClient{
+		HTTPClient:  client,
+		Rand:        rand.Float64,
+		Sleep:       sleep,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		MaxAttempts: 4,
+	}
+}
+
+// Do sends the request, retrying it while the failure is transient and the policy allows it
+func (c *retryingClient) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := c.HTTPClient.Do(req)
+		if attempt >= c.MaxAttempts || !c.retryable(req, res, err) {
+			return res, err
+		}
+
+		delay := c.backoff(attempt)
+		if res != nil {
+			if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > c.MaxDelay {
+					zap.S().Warnf("Cloudflare asked to retry %s %s after %s, giving up", req.Method, req.URL.Path, retryAfter)
+					return res, err
+				}
+				delay = retryAfter
+			}
+		}
+
+		next, ok := rewind(req)
+		if !ok {
+			return res, err
+		}
+
+		if err != nil {
+			zap.S().Warnf("Request %s %s failed, retrying in %s: %v", req.Method, req.URL.Path, delay.Round(time.Millisecond), err)
+		} else {
+			zap.S().Warnf("Request %s %s returned %d, retrying in %s", req.Method, req.URL.Path, res.StatusCode, delay.Round(time.Millisecond))
+			// the connection can only be reused once the body is read
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		if err := c.Sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		req = next
+	}
+}
+
+// retryable reports whether the outcome of the request is worth another attempt. A request rejected
+// by the rate limit wasn't processed, but a network error or a 5xx may come after the server applied it,
+// so only the idempotent requests are retried then.
+func (c *retryingClient) retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	switch {
+	case err != nil:
+		return idempotent(req)
+	case res.StatusCode == http.StatusTooManyRequests:
+		return true
+	case res.StatusCode >= 500 && res.StatusCode != http.StatusNotImplemented:
+		return idempotent(req)
+	}
+
+	return false
+}
+
+// backoff returns the delay before the retry following the attempt,
+// picked randomly between half and the whole of the exponential delay
+func (c *retryingClient) backoff(attempt int) time.Duration {
+	delay := c.MaxDelay
+	if attempt < 32 && c.BaseDelay<<(attempt-1) < c.MaxDelay {
+		delay = c.BaseDelay << (attempt - 1)
+	}
+
+	return delay/2 + time.Duration(c.Rand()*float64(delay/2))
+}
+
+// idempotent reports whether the request can be sent again without changing its effect
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, ok := req.Header[idempotencyKey]
+	return ok
+}
+
+// rewind returns a copy of the request with a fresh body, so it can be sent again.
+// It returns false when the body can't be read again.
+func rewind(req *http.Request) (*http.Request, bool) {
+	next := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return next, true
+	}
+
+	if req.GetBody == nil {
+		return nil, false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	next.Body = body
+
+	return next, true
+}
+
+// parseRetryAfter parses the Retry-After header, either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// sleep waits for the delay, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
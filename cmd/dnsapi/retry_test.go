@@ -0,0 +1,240 @@
+package dnsapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+// scriptedResponse is the outcome of an attempt: a response with the status code and headers, or an error
+type scriptedResponse struct {
+	err        error
+	header     http.Header
+	statusCode int
+}
+
+func TestRetryingClient_Do(t *testing.T) {
+	networkErr := errors.New("connection reset by peer")
+
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		idempotent       bool
+		responses        []scriptedResponse
+		expectedAttempts int
+		expectedDelays   []time.Duration
+		expectedCode     int
+		wantErr          bool
+	}{
+		{
+			name:             "Success",
+			method:           http.MethodGet,
+			responses:        []scriptedResponse{{statusCode: 200}},
+			expectedAttempts: 1,
+			expectedCode:     200,
+		},
+		{
+			name:   "RateLimitedHonoursRetryAfter",
+			method: http.MethodPost,
+			body:   `{"name":"home.example.com"}`,
+			responses: []scriptedResponse{
+				{statusCode: 429, header: http.Header{"Retry-After": {"7"}}},
+				{statusCode: 200},
+			},
+			expectedAttempts: 2,
+			expectedDelays:   []time.Duration{7 * time.Second},
+			expectedCode:     200,
+		},
+		{
+			name:   "RetryAfterTooLong",
+			method: http.MethodGet,
+			responses: []scriptedResponse{
+				{statusCode: 429, header: http.Header{"Retry-After": {"3600"}}},
+			},
+			expectedAttempts: 1,
+			expectedCode:     429,
+		},
+		{
+			name:   "ServerErrorBacksOff",
+			method: http.MethodGet,
+			responses: []scriptedResponse{
+				{statusCode: 502},
+				{statusCode: 503},
+				{statusCode: 200},
+			},
+			expectedAttempts: 3,
+			expectedDelays:   []time.Duration{750 * time.Millisecond, 1500 * time.Millisecond},
+			expectedCode:     200,
+		},
+		{
+			name:   "ServerErrorGivesUp",
+			method: http.MethodGet,
+			responses: []scriptedResponse{
+				{statusCode: 500}, {statusCode: 500}, {statusCode: 500}, {statusCode: 500},
+			},
+			expectedAttempts: 4,
+			expectedDelays:   []time.Duration{750 * time.Millisecond, 1500 * time.Millisecond, 3 * time.Second},
+			expectedCode:     500,
+		},
+		{
+			name:             "ServerErrorNotIdempotent",
+			method:           http.MethodPost,
+			body:             `{"name":"home.example.com"}`,
+			responses:        []scriptedResponse{{statusCode: 500}},
+			expectedAttempts: 1,
+			expectedCode:     500,
+		},
+		{
+			name:             "NetworkErrorNotIdempotent",
+			method:           http.MethodPatch,
+			body:             `{"content":"203.0.113.1"}`,
+			responses:        []scriptedResponse{{err: networkErr}},
+			expectedAttempts: 1,
+			wantErr:          true,
+		},
+		{
+			name:             "NetworkErrorIdempotent",
+			method:           http.MethodPatch,
+			body:             `{"content":"203.0.113.1"}`,
+			idempotent:       true,
+			responses:        []scriptedResponse{{err: networkErr}, {statusCode: 200}},
+			expectedAttempts: 2,
+			expectedDelays:   []time.Duration{750 * time.Millisecond},
+			expectedCode:     200,
+		},
+		{
+			name:             "ClientError",
+			method:           http.MethodGet,
+			responses:        []scriptedResponse{{statusCode: 400}},
+			expectedAttempts: 1,
+			expectedCode:     400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			client := newRetryingClient(&mocks.MockClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					response := tt.responses[attempts]
+					attempts++
+
+					if req.Body != nil {
+						body, _ := io.ReadAll(req.Body)
+						if string(body) != tt.body {
+							t.Errorf("attempt %d body = %q; want %q", attempts, body, tt.body)
+						}
+					}
+
+					if response.err != nil {
+						return nil, response.err
+					}
+					header := response.header
+					if header == nil {
+						header = http.Header{}
+					}
+					return &http.Response{StatusCode: response.statusCode, Header: header, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+				},
+			})
+			client.Rand = func() float64 { return 0.5 }
+			var delays []time.Duration
+			client.Sleep = func(_ context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			}
+
+			var body io.Reader
+			if tt.body != "" {
+				body = bytes.NewReader([]byte(tt.body))
+			}
+			req, err := http.NewRequest(tt.method, "https://api.cloudflare.com/client/v4/zones", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.idempotent {
+				req.Header[idempotencyKey] = nil
+			}
+
+			res, err := client.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if res != nil && res.StatusCode != tt.expectedCode {
+				t.Errorf("Do() status code = %d; want %d", res.StatusCode, tt.expectedCode)
+			}
+
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Do() attempts = %d; want %d", attempts, tt.expectedAttempts)
+			}
+
+			if len(delays) != len(tt.expectedDelays) {
+				t.Fatalf("Do() delays = %v; want %v", delays, tt.expectedDelays)
+			}
+			for i := range delays {
+				if delays[i] != tt.expectedDelays[i] {
+					t.Errorf("Do() delays = %v; want %v", delays, tt.expectedDelays)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryingClient_Do_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+
+	client := newRetryingClient(&mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{StatusCode: 503, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+		},
+	})
+	client.Sleep = func(ctx context.Context, _ time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.cloudflare.com/client/v4/zones", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v; want %v", err, context.Canceled)
+	}
+	if attempts != 1 {
+		t.Errorf("Do() attempts = %d; want 1", attempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		value         string
+		expectedDelay time.Duration
+		expectedOK    bool
+	}{
+		{value: ""},
+		{value: "soon"},
+		{value: "-1"},
+		{value: "30", expectedDelay: 30 * time.Second, expectedOK: true},
+		{value: "Fri, 02 Jan 2026 03:05:05 GMT", expectedDelay: time.Minute, expectedOK: true},
+		{value: "Fri, 02 Jan 2026 03:00:00 GMT", expectedDelay: 0, expectedOK: true},
+	}
+
+	for _, tt := range tests {
+		delay, ok := parseRetryAfter(tt.value, now)
+		if delay != tt.expectedDelay || ok != tt.expectedOK {
+			t.Errorf("parseRetryAfter(%q) = %s, %t; want %s, %t", tt.value, delay, ok, tt.expectedDelay, tt.expectedOK)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dnsapi
+
+import (
+	"path"
+	"strings"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/pkg/utils"
+)
+
+// RecordSelector selects the managed records by id and by name.
+// Names can be exact or glob patterns (e.g. *.home.example.com), and
+// names prefixed with ! exclude the matching records.
+type RecordSelector struct {
+	IDs     []string
+	Include []string
+	Exclude []string
+}
+
+// NewRecordSelector creates a new RecordSelector from the configured record ids and names
+func NewRecordSelector(ids, names []string) *RecordSelector {
+	selector := &RecordSelector{IDs: ids}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if exclude, ok := strings.CutPrefix(name, "!"); ok {
+			selector.Exclude = append(selector.Exclude, normalizeName(exclude))
+		} else if name != "" {
+			selector.Include = append(selector.Include, normalizeName(name))
+		}
+	}
+
+	return selector
+}
+
+// Match reports whether the record is selected. Without ids and included names every
+// record that is not excluded is selected.
+func (s *RecordSelector) Match(record Record) bool {
+	name := normalizeName(record.Name)
+
+	for _, pattern := range s.Exclude {
+		if matchName(pattern, name) {
+			return false
+		}
+	}
+
+	if len(s.IDs) == 0 && len(s.Include) == 0 {
+		return true
+	}
+
+	if utils.StringInSlice(record.ID, s.IDs) {
+		return true
+	}
+
+	for _, pattern := range s.Include {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Unmatched returns the included names that don't match any of the records
+func (s *RecordSelector) Unmatched(records []Record) (unmatched []string) {
+	for _, pattern := range s.Include {
+		found := false
+		for _, record := range records {
+			if matchName(pattern, normalizeName(record.Name)) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	return unmatched
+}
+
+// Names returns the included names that aren't patterns, i.e. the records that must exist
+func (s *RecordSelector) Names() (names []string) {
+	for _, name := range s.Include {
+		if !strings.ContainsAny(name, "*?[") {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// matchName reports whether the record name matches the exact name or glob pattern
+func matchName(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// normalizeName lowercases the name and removes the trailing dot of fully qualified names
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// hasZone reports whether the zone is already managed
+func (dns *CFDNS) hasZone(zoneID string) bool {
+	for _, zone := range dns.Zones {
+		if zone.ID == zoneID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// zoneConfig returns the configuration file entry of the zone, or nil
+func (dns *CFDNS) zoneConfig(zone Zone) *config.ZoneConfig {
+	for i, zoneConfig := range dns.Cfg.Zones {
+		if zoneConfig.ID != "" && zoneConfig.ID == zone.ID {
+			return &dns.Cfg.Zones[i]
+		}
+		if zoneConfig.ID == "" && strings.EqualFold(zoneConfig.Name, zone.Name) {
+			return &dns.Cfg.Zones[i]
+		}
+	}
+
+	return nil
+}
+
+// selectorFor returns the selector built from the record rules of the zone in the
+// configuration file, and whether the zone has any
+func (dns *CFDNS) selectorFor(zone Zone) (selector *RecordSelector, ok bool) {
+	zoneConfig := dns.zoneConfig(zone)
+	if zoneConfig == nil || len(zoneConfig.Records) == 0 {
+		return nil, false
+	}
+
+	var ids, names []string
+	for _, rule := range zoneConfig.Records {
+		if rule.ID != "" {
+			ids = append(ids, rule.ID)
+		}
+		if rule.Name != "" {
+			names = append(names, rule.Name)
+		}
+	}
+
+	return NewRecordSelector(ids, names), true
+}
+
+// recordOptions returns the first record rule of the zone matching the record, whose
+// proxied and ttl options are applied on update. The zero rule means no options.
+func (dns *CFDNS) recordOptions(zone Zone, record Record) (rule config.RecordRule) {
+	zoneConfig := dns.zoneConfig(zone)
+	if zoneConfig == nil {
+		return rule
+	}
+
+	for _, rule := range zoneConfig.Records {
+		if rule.IsExclusion() {
+			continue
+		}
+		if (rule.ID != "" && rule.ID == record.ID) || (rule.Name != "" && matchName(normalizeName(rule.Name), normalizeName(record.Name))) {
+			return rule
+		}
+	}
+
+	return rule
+}
@@ -0,0 +1,277 @@
+package dnsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecordSelector_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []string
+		names    []string
+		record   Record
+		expected bool
+	}{
+		{
+			name:     "NoFilter",
+			record:   Record{ID: "testRecordID", Name: "home.example.com"},
+			expected: true,
+		},
+		{
+			name:     "MatchID",
+			ids:      []string{"testRecordID"},
+			record:   Record{ID: "testRecordID", Name: "home.example.com"},
+			expected: true,
+		},
+		{
+			name:     "ExactName",
+			names:    []string{"home.example.com"},
+			record:   Record{ID: "testRecordID", Name: "home.example.com"},
+			expected: true,
+		},
+		{
+			name:     "ExactNameCaseInsensitive",
+			names:    []string{"Home.Example.com."},
+			record:   Record{ID: "testRecordID", Name: "home.example.com"},
+			expected: true,
+		},
+		{
+			name:     "ExactNameNoMatch",
+			names:    []string{"home.example.com"},
+			record:   Record{ID: "testRecordID", Name: "vpn.example.com"},
+			expected: false,
+		},
+		{
+			name:     "Wildcard",
+			names:    []string{"*.home.example.com"},
+			record:   Record{ID: "testRecordID", Name: "nas.home.example.com"},
+			expected: true,
+		},
+		{
+			name:     "WildcardDoesNotMatchParent",
+			names:    []string{"*.home.example.com"},
+			record:   Record{ID: "testRecordID", Name: "home.example.com"},
+			expected: false,
+		},
+		{
+			name:     "ExcludedName",
+			names:    []string{"*.home.example.com", "!nas.home.example.com"},
+			record:   Record{ID: "testRecordID", Name: "nas.home.example.com"},
+			expected: false,
+		},
+		{
+			name:     "OnlyExclusions",
+			names:    []string{"!nas.home.example.com"},
+			record:   Record{ID: "testRecordID", Name: "vpn.home.example.com"},
+			expected: true,
+		},
+		{
+			name:     "ExclusionWinsOverID",
+			ids:      []string{"testRecordID"},
+			names:    []string{"!*.example.com"},
+			record:   Record{ID: "testRecordID", Name: "home.example.com"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := NewRecordSelector(tt.ids, tt.names)
+
+			if matched := selector.Match(tt.record); matched != tt.expected {
+				t.Errorf("Match() = %t; want %t", matched, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecordSelector_Unmatched(t *testing.T) {
+	selector := NewRecordSelector(nil, []string{"home.example.com", "*.lab.example.com", "missing.example.com", "!vpn.example.com"})
+	records := []Record{
+		{ID: "testRecordID1", Name: "home.example.com"},
+		{ID: "testRecordID2", Name: "nas.lab.example.com"},
+	}
+
+	unmatched := selector.Unmatched(records)
+	if len(unmatched) != 1 || unmatched[0] != "missing.example.com" {
+		t.Errorf("Unmatched() = %v; want [missing.example.com]", unmatched)
+	}
+}
+
+func TestDns_getRecords_ZoneRules(t *testing.T) {
+	responses := map[string]string{
+		"testZoneID1": `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID1", "name": "home.testZoneName1", "type": "A", "content": "testContent"}, {"id":"testRecordID2", "name": "vpn.testZoneName1", "type": "A", "content": "testContent"}]}`,
+		"testZoneID2": `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID3", "name": "home.testZoneName2", "type": "A", "content": "testContent"}, {"id":"testRecordID4", "name": "vpn.testZoneName2", "type": "A", "content": "testContent"}]}`,
+	}
+
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			zoneID := strings.Split(req.URL.Path, "/")[4]
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(responses[zoneID])),
+			}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		AuthKey:     "testAuthKey",
+		Email:       "testEmail",
+		IPv4Enabled: true,
+		RecordNames: []string{"home.*"},
+		Zones: []config.ZoneConfig{
+			{Name: "testZoneName1", Records: []config.RecordRule{{Name: "vpn.testZoneName1"}}},
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        cfg,
+		HTTPClient: mockClient,
+		Records:    make(map[string][]Record),
+		Zones:      []Zone{{ID: "testZoneID1", Name: "testZoneName1"}, {ID: "testZoneID2", Name: "testZoneName2"}},
+	}
+
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+
+	// the zone with rules ignores RECORD_NAME, the other one uses it
+	assertRecordNames(t, "selected", RecordNames(dns.Records), map[string][]string{
+		"testZoneName1": {"vpn.testZoneName1"},
+		"testZoneName2": {"home.testZoneName2"},
+	})
+}
+
+func TestDns_getRecords_UnmatchedWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()), zapcore.AddSync(&logs), zap.WarnLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	responses := map[string]string{
+		"testZoneID1": `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID1", "name": "home.a.com", "type": "A", "content": "testContent"}]}`,
+		"testZoneID2": `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID2", "name": "home.b.com", "type": "A", "content": "testContent"}]}`,
+	}
+
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			zoneID := strings.Split(req.URL.Path, "/")[4]
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(responses[zoneID]))}, nil
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg: &config.Config{
+			APIToken:    "testToken",
+			IPv4Enabled: true,
+			RecordNames: []string{"home.b.com", "vpn.b.com"},
+			Zones: []config.ZoneConfig{
+				{Name: "a.com", Records: []config.RecordRule{{Name: "home.a.com"}, {Name: "typo.a.com"}}},
+			},
+		},
+		HTTPClient: mockClient,
+		Records:    make(map[string][]Record),
+		Zones:      []Zone{{ID: "testZoneID1", Name: "a.com"}, {ID: "testZoneID2", Name: "b.com"}},
+	}
+
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("getRecords() error = %v", err)
+	}
+
+	output := logs.String()
+	if !strings.Contains(output, "Record name typo.a.com doesn't match any A record in zone a.com") {
+		t.Errorf("getRecords() logged %q; want the unmatched zone rule typo.a.com", output)
+	}
+	if count := strings.Count(output, "vpn.b.com"); count != 1 || strings.Contains(output, "vpn.b.com doesn't match any A record in zone") {
+		t.Errorf("getRecords() logged %q; want vpn.b.com reported once, for the configured zones", output)
+	}
+	// a refresh finding the same names unmatched doesn't repeat the warnings
+	logs.Reset()
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("getRecords() error = %v", err)
+	}
+	if output := logs.String(); strings.Contains(output, "doesn't match") {
+		t.Errorf("getRecords() logged %q again; want the unchanged warnings not repeated", output)
+	}
+
+	// the names about to be created aren't unmatched
+	logs.Reset()
+	dns.Cfg.CreateMissingRecords = true
+	if _, err := dns.getRecords(context.Background()); err != nil {
+		t.Fatalf("getRecords() error = %v", err)
+	}
+	if output := logs.String(); strings.Contains(output, "doesn't match") {
+		t.Errorf("getRecords() logged %q; want no warning for the names being created", output)
+	}
+	assertRecordNames(t, "missing", RecordNames(dns.Missing), map[string][]string{"a.com": {"typo.a.com"}, "b.com": {"vpn.b.com"}})
+}
+
+func TestDns_UpdateRecords_RecordOptions(t *testing.T) {
+	proxied := true
+	var payloads []map[string]interface{}
+
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			var payload map[string]interface{}
+			if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+				t.Fatalf("invalid payload: %v", err)
+			}
+			payloads = append(payloads, payload)
+
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1", "name": "home.testZoneName", "type": "A", "content": "203.0.113.1", "proxied": true, "ttl": 120}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		AuthKey: "testAuthKey",
+		Email:   "testEmail",
+		Zones: []config.ZoneConfig{
+			{
+				ID: "testZoneID",
+				Records: []config.RecordRule{
+					{Name: "home.testZoneName", Proxied: &proxied, TTL: 120},
+					{Name: "vpn.testZoneName"},
+				},
+			},
+		},
+	}
+
+	dns := &CFDNS{
+		Cfg:        cfg,
+		HTTPClient: mockClient,
+		Records: map[string][]Record{
+			"testZoneName": {
+				{ID: "testRecordID1", Name: "home.testZoneName", Type: "A", Content: "203.0.113.1", ZoneID: "testZoneID", TTL: 1},
+				{ID: "testRecordID2", Name: "vpn.testZoneName", Type: "A", Content: "203.0.113.1", ZoneID: "testZoneID", TTL: 1},
+			},
+		},
+	}
+
+	result, err := dns.UpdateRecords(context.Background(), "203.0.113.1")
+	if err != nil {
+		t.Fatalf("UpdateRecords() error = %v", err)
+	}
+
+	// the ip didn't change, but the options of the first record did
+	assertRecordNames(t, "updated", RecordNames(result.Updated), map[string][]string{"testZoneName": {"home.testZoneName"}})
+	assertRecordNames(t, "unchanged", RecordNames(result.Unchanged), map[string][]string{"testZoneName": {"vpn.testZoneName"}})
+
+	if len(payloads) != 1 {
+		t.Fatalf("UpdateRecords() sent %d requests; want 1", len(payloads))
+	}
+
+	if payloads[0]["content"] != "203.0.113.1" || payloads[0]["proxied"] != true || payloads[0]["ttl"] != float64(120) {
+		t.Errorf("payload = %v; want content, proxied and ttl", payloads[0])
+	}
+}
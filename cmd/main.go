@@ -1,121 +1,131 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
-	"net/http"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
+	"path/filepath"
+	"strings"
 
-	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
-	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/logger"
-	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/notifier"
 	"go.uber.org/zap"
 )
 
-func main() {
-	log := logger.New("LOG_LEVEL")
-	defer func(logger *zap.SugaredLogger) {
-		err := logger.Sync()
-		if err != nil {
-			panic(err)
-		}
-	}(log)
+// Exit codes of the commands
+const (
+	// exitOK means the command succeeded
+	exitOK = 0
+	// exitFailure means the command failed, e.g. the sync couldn't update every record
+	exitFailure = 1
+	// exitUsage means the command line is invalid
+	exitUsage = 2
+	// exitConfig means the configuration is invalid, or the zones and records couldn't be loaded with it
+	exitConfig = 3
+)
 
-	zap.S().Info("Starting Cloudflare CFDNS Auto Updater")
+// stdout receives the output of the commands
+var stdout io.Writer = os.Stdout
+
+// command is a subcommand of the CLI
+type command struct {
+	run         func(args []string) int
+	name        string
+	description string
+	// quiet commands print their result on stdout, so the logs go to stderr
+	quiet bool
+}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+// commands lists the subcommands, the first one is the default
+var commands = []command{
+	{name: "run", description: "keep the records updated until stopped (default)", run: runDaemon},
+	{name: "once", description: "sync the records once and exit, with a non-zero status if it failed", run: runOnce},
+	{name: "list-zones", description: "list the managed zones", run: runListZones, quiet: true},
+	{name: "list-records", description: "list the managed records", run: runListRecords, quiet: true},
+	{name: "validate-config", description: "check the configuration without contacting Cloudflare", run: runValidateConfig, quiet: true},
+	{name: "current-ip", description: "print the current public ip of every enabled family", run: runCurrentIP, quiet: true},
+	{name: "healthcheck", description: "check the health of the instance running in the same container", run: runHealthcheck, quiet: true},
+}
 
-	var wg sync.WaitGroup
+func main() {
+	os.Exit(cli(os.Args[1:]))
+}
 
-	currentIpChan := make(chan string)
-	go getCurrentIp(ctx, currentIpChan)
-	lastIp := ""
+// cli runs the command named by the first argument, and returns the exit code of the process.
+// Without a command, or when the first argument is a flag, the updater runs as a daemon.
+func cli(args []string) int {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		usage(stdout)
+		return exitOK
+	}
 
-	cfg, err := config.New()
-	if err != nil {
-		zap.S().Fatal(err)
+	cmd, args, ok := findCommand(args)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
 	}
 
-	dns, err := dnsapi.New(cfg)
-	if err != nil {
-		zap.S().Fatal(err)
+	var log *zap.SugaredLogger
+	if cmd.quiet {
+		log = logger.NewWithOutput("LOG_LEVEL", os.Stderr)
+	} else {
+		log = logger.New("LOG_LEVEL")
 	}
+	// syncing a console fails on some platforms, and there is nothing left to do about it
+	defer func() { _ = log.Sync() }()
+
+	return cmd.run(args)
+}
 
-	var notify *notifier.Notifier
-	if cfg.SenderAddress != "" && cfg.SenderPassword != "" && cfg.ReceiverAddress != "" {
-		notify = notifier.New(cfg)
+// findCommand returns the command named by the first argument and the remaining arguments
+func findCommand(args []string) (cmd command, rest []string, ok bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, true
 	}
 
-	for {
-		select {
-		case ip := <-currentIpChan:
-			if ip != lastIp {
-				zap.S().Infof("New IP detected: %s", ip)
-				lastIp = ip
-				wg.Add(1)
-				go func(ip string) {
-					defer wg.Done()
-					if updatedRecords, err := dns.UpdateRecords(ip); err == nil {
-						if notify != nil {
-							wg.Add(1)
-							go func(updatedRecords map[string][]string) {
-								defer wg.Done()
-								notify.SendEmail(updatedRecords, ip)
-							}(updatedRecords)
-						}
-					}
-				}(ip)
-			}
-		case <-ctx.Done():
-			zap.S().Info("Shutting down...")
-			wg.Wait()
-			return
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd, args[1:], true
 		}
 	}
-}
 
-// getCurrentIp fetches the current public ip address every second,
-// and sends it to the currentIpChan channel
-func getCurrentIp(ctx context.Context, currentIpChan chan<- string) {
-	url := "https://api.ipify.org"
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	return command{}, args, false
+}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		zap.S().Fatal(err)
+// usage prints the available commands
+func usage(w io.Writer) {
+	name := filepath.Base(os.Args[0])
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", name)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.description)
 	}
+	fmt.Fprintf(w, "\nRun %s <command> -h for the flags of a command.\n", name)
+}
+
+// newFlagSet creates the flags of a command, with the -config flag shared by all of them
+func newFlagSet(name string) (flags *flag.FlagSet, configFile *string) {
+	flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile = flags.String("config", "", "path of the YAML configuration file (overrides CONFIG_FILE)")
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			res, err := http.DefaultClient.Do(req)
-			if err != nil {
-				zap.S().Fatal(err)
-			}
-
-			if res.StatusCode != http.StatusOK {
-				zap.S().Error("Error getting current ip. Status code: %d", res.StatusCode)
-				continue
-			}
-
-			var bodyString string
-			bodyBytes, err := io.ReadAll(res.Body)
-			if err != nil {
-				zap.S().Fatal(err)
-			}
-			bodyString = string(bodyBytes)
-			res.Body.Close()
-
-			currentIpChan <- bodyString
+	return flags, configFile
+}
+
+// parseFlags parses the arguments of a command. When it returns false the command must
+// exit with the returned code, e.g. after printing the help.
+func parseFlags(flags *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
 		}
+		return exitUsage, false
 	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return exitUsage, false
+	}
+
+	return exitOK, true
 }
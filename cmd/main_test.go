@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/pkg/env"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedName string
+		expectedRest string
+		expectedOK   bool
+	}{
+		{name: "NoArguments", expectedName: "run", expectedOK: true},
+		{name: "FlagsOnly", args: []string{"-config", "config.yaml"}, expectedName: "run", expectedRest: "-config config.yaml", expectedOK: true},
+		{name: "Command", args: []string{"list-records", "-json"}, expectedName: "list-records", expectedRest: "-json", expectedOK: true},
+		{name: "Unknown", args: []string{"update"}, expectedRest: "update"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, rest, ok := findCommand(tt.args)
+			if cmd.name != tt.expectedName || strings.Join(rest, " ") != tt.expectedRest || ok != tt.expectedOK {
+				t.Errorf("findCommand(%v) = %s, %v, %t; want %s, %s, %t", tt.args, cmd.name, rest, ok, tt.expectedName, tt.expectedRest, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestCli_ValidateConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		expectedCode int
+		expectedOut  string
+	}{
+		{
+			name:         "Valid",
+			args:         []string{"validate-config"},
+			env:          map[string]string{"API_TOKEN": "testToken", "ZONE_NAME": "example.com"},
+			expectedCode: exitOK,
+			expectedOut:  "Configuration is valid",
+		},
+		{
+			name:         "Invalid",
+			args:         []string{"validate-config"},
+			env:          map[string]string{"ZONE_NAME": "example.com"},
+			expectedCode: exitConfig,
+		},
+		{
+			name:         "UnknownIPProvider",
+			args:         []string{"validate-config"},
+			env:          map[string]string{"API_TOKEN": "testToken", "ZONE_NAME": "example.com", "IP_PROVIDERS": "nowhere"},
+			expectedCode: exitConfig,
+		},
+		{
+			name:         "UnexpectedArgument",
+			args:         []string{"validate-config", "config.yaml"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "UnknownCommand",
+			args:         []string{"update"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Help",
+			args:         []string{"help"},
+			expectedCode: exitOK,
+			expectedOut:  "list-records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the cases don't depend on the environment of the developer
+			for _, name := range []string{"CONFIG_FILE", "API_TOKEN", "AUTH_KEY", "EMAIL", "ZONE_ID", "ZONE_NAME", "IP_PROVIDERS"} {
+				t.Setenv(name, "")
+				t.Setenv(name+env.FileSuffix, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var out bytes.Buffer
+			defer func(original io.Writer) { stdout = original }(stdout)
+			stdout = &out
+
+			if code := cli(tt.args); code != tt.expectedCode {
+				t.Errorf("cli(%v) = %d; want %d", tt.args, code, tt.expectedCode)
+			}
+			if !strings.Contains(out.String(), tt.expectedOut) {
+				t.Errorf("cli(%v) printed %q; want %q", tt.args, out.String(), tt.expectedOut)
+			}
+		})
+	}
+}
+
+func TestSortedRecords(t *testing.T) {
+	records := sortedRecords(map[string][]dnsapi.Record{
+		"example.org": {{Name: "home.example.org", Type: "A"}},
+		"example.com": {{Name: "vpn.example.com", Type: "A"}, {Name: "home.example.com", Type: "AAAA"}, {Name: "home.example.com", Type: "A"}},
+	})
+
+	var got []string
+	for _, record := range records {
+		got = append(got, record.ZoneName+"/"+record.Name+"/"+record.Type)
+	}
+
+	expected := "example.com/home.example.com/A,example.com/home.example.com/AAAA,example.com/vpn.example.com/A,example.org/home.example.org/A"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("sortedRecords() = %v; want %s", got, expected)
+	}
+}
+
+func TestIsLocalAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "127.0.0.1:9091", expected: true},
+		{addr: "[::1]:9091", expected: true},
+		{addr: "localhost:9091", expected: true},
+		{addr: "unix:/run/cfdns/api.sock", expected: true},
+		{addr: ":9091", expected: false},
+		{addr: "0.0.0.0:9091", expected: false},
+		{addr: "192.168.1.10:9091", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := isLocalAddr(tt.addr); got != tt.expected {
+			t.Errorf("isLocalAddr(%s) = %t; want %t", tt.addr, got, tt.expected)
+		}
+	}
+}
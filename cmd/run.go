@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/api"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/health"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/ipsource"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/notifier"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/scheduler"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/server"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/state"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/updater"
+	"go.uber.org/zap"
+)
+
+// runDaemon keeps the records updated until the process is stopped
+func runDaemon(args []string) int {
+	flags, configFile := newFlagSet("run")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	zap.S().Info("Starting Cloudflare CFDNS Auto Updater")
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		zap.S().Error(err)
+		return exitConfig
+	}
+
+	if cfg.DryRun {
+		zap.S().Warn("Dry run mode enabled, no record will be changed")
+	}
+
+	// the ip must be checked at least once per interval, allowing for the jitter and a slow sync
+	maxCheckAge := cfg.CheckInterval + cfg.CheckInterval*time.Duration(cfg.CheckJitter)/100 + 5*time.Minute
+	status := health.New(maxCheckAge)
+
+	// the server starts first, so the health endpoints answer while the records are loaded
+	serverDone := make(chan struct{})
+	if cfg.HTTPAddr != "" {
+		srv := server.New(cfg.HTTPAddr)
+		srv.Mux.Handle("/healthz", status.LiveHandler())
+		srv.Mux.Handle("/readyz", status.ReadyHandler())
+		go func() {
+			defer close(serverDone)
+			if err := srv.Run(ctx); err != nil {
+				zap.S().Errorf("HTTP server error: %v", err)
+			}
+		}()
+	} else {
+		close(serverDone)
+	}
+
+	upd, err := newUpdater(ctx, cfg)
+	if err != nil {
+		zap.S().Error(err)
+		stop()
+		<-serverDone
+		return exitConfig
+	}
+	status.SetLoaded(len(upd.DNS.Zones), upd.DNS.RecordCount())
+	sched := scheduler.New(cfg.CheckInterval, float64(cfg.CheckJitter)/100)
+
+	controlDone := make(chan struct{})
+	if cfg.ControlAPIAddr != "" {
+		if !isLocalAddr(cfg.ControlAPIAddr) {
+			zap.S().Warnf("The control API listens on %s, which is reachable from other hosts", cfg.ControlAPIAddr)
+		}
+		srv := server.NewWithHandler(cfg.ControlAPIAddr, api.New(upd, sched.Trigger, cfg.ControlAPIToken).Handler())
+		go func() {
+			defer close(controlDone)
+			if err := srv.Run(ctx); err != nil {
+				zap.S().Errorf("Control API error: %v", err)
+			}
+		}()
+	} else {
+		close(controlDone)
+	}
+
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	defer signal.Stop(hupChan)
+
+	go func() {
+		for {
+			select {
+			case <-hupChan:
+				zap.S().Info("SIGHUP received, checking the current ip now")
+				sched.Trigger()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	refreshDone := make(chan struct{})
+	if cfg.RefreshInterval > 0 {
+		refresher := scheduler.New(cfg.RefreshInterval, float64(cfg.CheckJitter)/100)
+		go func() {
+			defer close(refreshDone)
+			loaded := true
+			refresher.Run(ctx, func(ctx context.Context) error {
+				// the scheduler runs the task right away, when the records were just loaded
+				if loaded {
+					loaded = false
+					return nil
+				}
+
+				err := upd.Refresh(ctx)
+				setLoaded(status, upd)
+				return err
+			})
+		}()
+	} else {
+		close(refreshDone)
+	}
+
+	sched.Run(ctx, func(ctx context.Context) error {
+		err := upd.Sync(ctx)
+		// the missing records created by the sync are now managed
+		setLoaded(status, upd)
+		status.RecordSync(err)
+		return err
+	})
+
+	zap.S().Info("Shutting down...")
+	upd.Wait()
+	<-refreshDone
+	<-serverDone
+	<-controlDone
+
+	return exitOK
+}
+
+// signalContext returns a context canceled when the process is interrupted or terminated
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// newResolvers creates the resolvers of the enabled families
+func newResolvers(cfg *config.Config) (resolvers []*ipsource.Resolver, err error) {
+	var families []ipsource.Family
+	if cfg.IPv4Enabled {
+		families = append(families, ipsource.IPv4)
+	}
+	if cfg.IPv6Enabled {
+		families = append(families, ipsource.IPv6)
+	}
+
+	for _, family := range families {
+		resolver, err := ipsource.New(cfg, family)
+		if err != nil {
+			return nil, err
+		}
+		resolvers = append(resolvers, resolver)
+	}
+
+	return resolvers, nil
+}
+
+// newUpdater loads the zones and records and creates the updater with its notification channels
+func newUpdater(ctx context.Context, cfg *config.Config) (*updater.Updater, error) {
+	resolvers, err := newResolvers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dns, err := dnsapi.New(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatcher, err := notifier.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	upd := updater.New(dns, resolvers, notifier.NewThrottle(dispatcher, cfg.NotifyRepeatInterval), state.New(cfg.StateFile))
+	upd.OutageThreshold = cfg.NotifyOutageAfter
+
+	return upd, nil
+}
+
+// setLoaded updates the zones and records counted by the readiness with the ones managed now
+func setLoaded(status *health.Health, upd *updater.Updater) {
+	snapshot := upd.Status()
+	status.SetLoaded(len(snapshot.Zones), recordCount(snapshot.Records))
+}
+
+// recordCount returns the number of records across all the zones
+func recordCount(records map[string][]dnsapi.Record) (count int) {
+	for _, zoneRecords := range records {
+		count += len(zoneRecords)
+	}
+
+	return count
+}
+
+// isLocalAddr reports whether the address is a Unix socket or a loopback address
+func isLocalAddr(addr string) bool {
+	if strings.HasPrefix(addr, server.UnixPrefix) {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -5,6 +5,7 @@ go 1.20
 require (
 	go.elastic.co/ecszap v1.0.2
 	go.uber.org/zap v1.27.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/updater"
+	"go.uber.org/zap"
+)
+
+// Updater is the part of the updater controlled by the API
+type Updater interface {
+	Status() updater.Status
+	Force()
+}
+
+// API is the local HTTP API to inspect the updater and force a sync
+type API struct {
+	Updater Updater
+	// Trigger starts a sync without waiting for the next check
+	Trigger func()
+	Token   string
+}
+
+// New creates a new API, every request must carry the token as a bearer token
+func New(upd Updater, trigger func(), token string) *API {
+	return &API{
+		Updater: upd,
+		Trigger: trigger,
+		Token:   token,
+	}
+}
+
+// errorResponse is the body of a failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler serves GET /status and POST /sync
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/status", method(http.MethodGet, http.HandlerFunc(a.status)))
+	mux.Handle("/sync", method(http.MethodPost, http.HandlerFunc(a.sync)))
+
+	return a.authenticate(mux)
+}
+
+// status responds with the managed zones and records, the current ips and the last update results
+func (a *API) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.Updater.Status())
+}
+
+// sync forces the update of every record with a fresh ip, and responds before it's done
+func (a *API) sync(w http.ResponseWriter, _ *http.Request) {
+	zap.S().Info("Sync requested through the control API")
+	a.Updater.Force()
+	a.Trigger()
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "sync scheduled"})
+}
+
+// authenticate rejects the requests without the bearer token
+func (a *API) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid or missing bearer token"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// method rejects the requests with another method
+func method(allowed string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != allowed {
+			w.Header().Set("Allow", allowed)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON responds with the value encoded as JSON
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		zap.S().Errorf("Error encoding the response: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/updater"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+// fakeUpdater returns a fixed status and counts the forced syncs
+type fakeUpdater struct {
+	status updater.Status
+	forced int
+}
+
+func (f *fakeUpdater) Status() updater.Status { return f.status }
+func (f *fakeUpdater) Force()                 { f.forced++ }
+
+func TestAPI_Handler(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		expectedCode int
+		expectedSync bool
+	}{
+		{name: "Status", method: http.MethodGet, path: "/status", token: "secret", expectedCode: http.StatusOK},
+		{name: "Sync", method: http.MethodPost, path: "/sync", token: "secret", expectedCode: http.StatusAccepted, expectedSync: true},
+		{name: "MissingToken", method: http.MethodPost, path: "/sync", expectedCode: http.StatusUnauthorized},
+		{name: "WrongToken", method: http.MethodGet, path: "/status", token: "guess", expectedCode: http.StatusUnauthorized},
+		{name: "WrongMethod", method: http.MethodGet, path: "/sync", token: "secret", expectedCode: http.StatusMethodNotAllowed},
+		{name: "NotFound", method: http.MethodGet, path: "/records", token: "secret", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upd := &fakeUpdater{status: updater.Status{
+				IPs:     map[string]string{"IPv4": "1.2.3.4"},
+				Records: map[string][]dnsapi.Record{"example.com": {{ID: "1", Name: "home.example.com", Content: "1.2.3.4"}}},
+			}}
+			triggered := 0
+			handler := New(upd, func() { triggered++ }, "secret").Handler()
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("%s %s = %d; want %d", tt.method, tt.path, rec.Code, tt.expectedCode)
+			}
+
+			synced := upd.forced == 1 && triggered == 1
+			if synced != tt.expectedSync {
+				t.Errorf("forced = %d, triggered = %d; want a sync %t", upd.forced, triggered, tt.expectedSync)
+			}
+
+			if tt.path == "/status" && rec.Code == http.StatusOK {
+				var status updater.Status
+				if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+					t.Fatalf("decoding the status error = %v", err)
+				}
+				if status.IPs["IPv4"] != "1.2.3.4" || len(status.Records["example.com"]) != 1 {
+					t.Errorf("GET /status = %+v; want the status of the updater", status)
+				}
+			}
+		})
+	}
+}
@@ -1,42 +1,239 @@
 package config
 
 import (
-	"errors"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/pkg/env"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/pkg/utils"
 	"go.uber.org/zap"
 )
 
 type Config struct {
-	AuthKey         string
-	Email           string
-	ReceiverAddress string
-	SenderAddress   string
-	SenderPassword  string
-	RecordIDs       []string
-	ZoneIDs         []string
-	ZoneNames       []string
-	CheckInterval   int
-}
-
-func New() (config *Config, err error) {
+	APIToken                 string
+	AuthKey                  string
+	ControlAPIAddr           string
+	ControlAPIToken          string
+	DiscordWebhookURL        string
+	Email                    string
+	GotifyToken              string
+	GotifyURL                string
+	HTTPAddr                 string
+	IPProviderRegex          string
+	IPProviderURL            string
+	NotificationTemplateFile string
+	NtfyToken                string
+	NtfyURL                  string
+	SenderAddress            string
+	SenderName               string
+	SenderPassword           string
+	SlackWebhookURL          string
+	SMTPAuth                 string
+	SMTPHost                 string
+	SMTPTLS                  string
+	StateFile                string
+	TelegramBotToken         string
+	TelegramChatID           string
+	WebhookURL               string
+	IPProviders              []string
+	ReceiverAddress          []string
+	RecordIDs                []string
+	RecordNames              []string
+	ZoneIDs                  []string
+	ZoneNames                []string
+	APIRequestTimeout        time.Duration
+	APITimeout               time.Duration
+	CheckInterval            time.Duration
+	NotifyOutageAfter        time.Duration
+	NotifyRepeatInterval     time.Duration
+	RefreshInterval          time.Duration
+	CheckJitter              int
+	CreateMissingRecords     bool
+	DeleteRemovedRecords     bool
+	DryRun                   bool
+	IPv4Enabled              bool
+	IPv6Enabled              bool
+	IPQuorum                 int
+	SMTPPort                 int
+	Zones                    []ZoneConfig
+}
+
+const (
+	// SMTPTLSImplicit connects to the SMTP server over TLS
+	SMTPTLSImplicit = "tls"
+	// SMTPTLSStartTLS upgrades the SMTP connection with STARTTLS
+	SMTPTLSStartTLS = "starttls"
+	// SMTPTLSNone doesn't encrypt the SMTP connection
+	SMTPTLSNone = "none"
+
+	// SMTPAuthPlain authenticates with the PLAIN mechanism
+	SMTPAuthPlain = "plain"
+	// SMTPAuthLogin authenticates with the LOGIN mechanism
+	SMTPAuthLogin = "login"
+	// SMTPAuthNone doesn't authenticate
+	SMTPAuthNone = "none"
+)
+
+// New loads the configuration from the environment variables, on top of the
+// optional configuration file. An empty configFile falls back to CONFIG_FILE.
+// Every problem found is reported at once in the returned error.
+func New(configFile string) (config *Config, err error) {
 	zap.S().Info("Loading configuration")
+
+	if configFile == "" {
+		configFile, err = env.GetEnv("CONFIG_FILE", false, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file := &File{}
+	if configFile != "" {
+		file, err = LoadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l := env.NewLoader()
 	config = &Config{
-		AuthKey:         env.GetEnv("AUTH_KEY", true, ""),
-		CheckInterval:   env.GetEnvAsInt("CHECK_INTERVAL", false, 86400),
-		Email:           env.GetEnv("EMAIL", true, ""),
-		ReceiverAddress: env.GetEnv("RECEIVER_ADDRESS", false, ""),
-		RecordIDs:       env.GetEnvAsStringSlice("RECORD_ID", false, []string{}),
-		SenderAddress:   env.GetEnv("SENDER_ADDRESS", false, ""),
-		SenderPassword:  env.GetEnv("SENDER_PASSWORD", false, ""),
-		ZoneIDs:         env.GetEnvAsStringSlice("ZONE_ID", false, []string{}),
-		ZoneNames:       env.GetEnvAsStringSlice("ZONE_NAME", false, []string{}),
+		APIRequestTimeout:        l.Duration("API_REQUEST_TIMEOUT", false, 30*time.Second),
+		APITimeout:               l.Duration("API_TIMEOUT", false, 5*time.Minute),
+		APIToken:                 l.String("API_TOKEN", false, ""),
+		AuthKey:                  l.String("AUTH_KEY", false, ""),
+		CheckInterval:            l.Duration("CHECK_INTERVAL", false, time.Duration(intOr(file.CheckInterval, 86400))*time.Second),
+		CheckJitter:              l.Int("CHECK_JITTER", false, intOr(file.CheckJitter, 10)),
+		ControlAPIAddr:           l.String("CONTROL_API_ADDR", false, ""),
+		ControlAPIToken:          l.String("CONTROL_API_TOKEN", false, ""),
+		CreateMissingRecords:     l.Bool("CREATE_MISSING_RECORDS", false, boolOr(file.CreateMissing, false)),
+		DeleteRemovedRecords:     l.Bool("DELETE_REMOVED_RECORDS", false, boolOr(file.DeleteRemoved, false)),
+		DiscordWebhookURL:        urlString(l.URL("DISCORD_WEBHOOK_URL", false, nil)),
+		DryRun:                   l.Bool("DRY_RUN", false, boolOr(file.DryRun, false)),
+		Email:                    l.String("EMAIL", false, ""),
+		GotifyToken:              l.String("GOTIFY_TOKEN", false, ""),
+		HTTPAddr:                 l.String("HTTP_ADDR", false, ""),
+		GotifyURL:                urlString(l.URL("GOTIFY_URL", false, nil)),
+		IPProviderRegex:          l.String("IP_PROVIDER_REGEX", false, ""),
+		IPProviderURL:            urlString(l.URL("IP_PROVIDER_URL", false, nil)),
+		IPProviders:              l.StringSlice("IP_PROVIDERS", false, sliceOr(file.IPProviders, []string{"ipify", "icanhazip", "cloudflare"})),
+		IPQuorum:                 l.Int("IP_QUORUM", false, intOr(file.IPQuorum, 0)),
+		IPv4Enabled:              l.Bool("IPV4_ENABLED", false, boolOr(file.IPv4Enabled, true)),
+		IPv6Enabled:              l.Bool("IPV6_ENABLED", false, boolOr(file.IPv6Enabled, false)),
+		NotifyOutageAfter:        l.Duration("NOTIFY_OUTAGE_AFTER", false, time.Hour),
+		NotifyRepeatInterval:     l.Duration("NOTIFY_REPEAT_INTERVAL", false, time.Hour),
+		NotificationTemplateFile: l.String("NOTIFICATION_TEMPLATE_FILE", false, ""),
+		NtfyToken:                l.String("NTFY_TOKEN", false, ""),
+		NtfyURL:                  urlString(l.URL("NTFY_URL", false, nil)),
+		RefreshInterval:          l.Duration("REFRESH_INTERVAL", false, time.Hour),
+		ReceiverAddress:          l.StringSlice("RECEIVER_ADDRESS", false, []string{}),
+		RecordIDs:                l.StringSlice("RECORD_ID", false, []string{}),
+		RecordNames:              l.StringSlice("RECORD_NAME", false, []string{}),
+		SenderAddress:            l.String("SENDER_ADDRESS", false, ""),
+		SenderName:               l.String("SENDER_NAME", false, ""),
+		SenderPassword:           l.String("SENDER_PASSWORD", false, ""),
+		SlackWebhookURL:          urlString(l.URL("SLACK_WEBHOOK_URL", false, nil)),
+		SMTPAuth:                 strings.ToLower(l.String("SMTP_AUTH", false, SMTPAuthPlain)),
+		SMTPHost:                 l.String("SMTP_HOST", false, "smtp.gmail.com"),
+		SMTPTLS:                  strings.ToLower(l.String("SMTP_TLS", false, SMTPTLSStartTLS)),
+		StateFile:                l.String("STATE_FILE", false, file.StateFile),
+		TelegramBotToken:         l.String("TELEGRAM_BOT_TOKEN", false, ""),
+		TelegramChatID:           l.String("TELEGRAM_CHAT_ID", false, ""),
+		WebhookURL:               urlString(l.URL("WEBHOOK_URL", false, nil)),
+		ZoneIDs:                  l.StringSlice("ZONE_ID", false, []string{}),
+		ZoneNames:                l.StringSlice("ZONE_NAME", false, []string{}),
+		Zones:                    file.Zones,
+	}
+	config.SMTPPort = l.Int("SMTP_PORT", false, defaultSMTPPort(config.SMTPTLS))
+
+	for _, zone := range file.Zones {
+		if zone.ID != "" {
+			config.ZoneIDs = append(config.ZoneIDs, zone.ID)
+		} else {
+			config.ZoneNames = append(config.ZoneNames, zone.Name)
+		}
 	}
 	zap.S().Debug("Config loaded")
 
-	if len(config.ZoneIDs) == 0 && len(config.ZoneNames) == 0 {
-		return config, errors.New("no zone ids or zone names provided")
+	l.Check(config.APIToken != "" || (config.Email != "" && config.AuthKey != ""),
+		"either an API token or both email and auth key must be provided")
+	l.Check(len(config.ZoneIDs) > 0 || len(config.ZoneNames) > 0, "no zone ids or zone names provided")
+	l.Check(config.IPv4Enabled || config.IPv6Enabled, "at least one of IPv4 and IPv6 must be enabled")
+	l.Check(config.CheckInterval > 0, "check interval must be greater than 0")
+	l.Check(config.CheckJitter >= 0 && config.CheckJitter <= 100, "check jitter must be a percentage between 0 and 100")
+	l.Check(utils.StringInSlice(config.SMTPTLS, []string{SMTPTLSImplicit, SMTPTLSStartTLS, SMTPTLSNone}),
+		"smtp tls mode must be one of tls, starttls or none, got %s", config.SMTPTLS)
+	l.Check(utils.StringInSlice(config.SMTPAuth, []string{SMTPAuthPlain, SMTPAuthLogin, SMTPAuthNone}),
+		"smtp auth must be one of plain, login or none, got %s", config.SMTPAuth)
+	l.Check(config.SMTPPort > 0 && config.SMTPPort <= 65535, "smtp port must be between 1 and 65535")
+	l.Check(!config.EmailEnabled() || config.SMTPAuth == SMTPAuthNone || config.SenderPassword != "",
+		"the sender password is required when smtp auth is enabled")
+	l.Check(config.APIRequestTimeout > 0, "api request timeout must be greater than 0")
+	l.Check(config.APITimeout >= 0, "api timeout must not be negative")
+	l.Check(config.RefreshInterval >= 0, "refresh interval must not be negative")
+	l.Check(config.NotifyOutageAfter >= 0, "notify outage after must not be negative")
+	l.Check(config.NotifyRepeatInterval >= 0, "notify repeat interval must not be negative")
+	l.Check((config.TelegramBotToken == "") == (config.TelegramChatID == ""),
+		"both the telegram bot token and chat id must be provided")
+	l.Check((config.GotifyURL == "") == (config.GotifyToken == ""),
+		"both the gotify url and token must be provided")
+	l.Check(!config.DeleteRemovedRecords || config.CreateMissingRecords,
+		"deleting the removed records requires creating the missing records, only the records created by the updater are deleted")
+	l.Check(config.ControlAPIAddr == "" || config.ControlAPIToken != "",
+		"the control api token is required when the control api is enabled")
+
+	return config, l.Err()
+}
+
+// EmailEnabled reports whether the email notifications are configured
+func (c *Config) EmailEnabled() bool {
+	return c.SenderAddress != "" && len(c.ReceiverAddress) > 0
+}
+
+// defaultSMTPPort returns the standard port of the SMTP TLS mode
+func defaultSMTPPort(tlsMode string) int {
+	switch tlsMode {
+	case SMTPTLSImplicit:
+		return 465
+	case SMTPTLSNone:
+		return 25
+	default:
+		return 587
+	}
+}
+
+// urlString returns the string form of the URL, or an empty string when it's nil
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	return u.String()
+}
+
+// intOr returns the value of the pointer, or the fallback when it's nil
+func intOr(value *int, fallback int) int {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
+
+// boolOr returns the value of the pointer, or the fallback when it's nil
+func boolOr(value *bool, fallback bool) bool {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
+
+// sliceOr returns the slice, or the fallback when it's empty
+func sliceOr(value []string, fallback []string) []string {
+	if len(value) == 0 {
+		return fallback
 	}
 
-	return config, nil
+	return value
 }
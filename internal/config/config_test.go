@@ -0,0 +1,126 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv("API_TOKEN", "testToken")
+	t.Setenv("ZONE_NAME", "example.com, ,example.org")
+	t.Setenv("CHECK_INTERVAL", "6h")
+	t.Setenv("IP_PROVIDER_URL", "https://ip.example.com/raw")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if cfg.CheckInterval != 6*time.Hour {
+		t.Errorf("CheckInterval = %v; want 6h", cfg.CheckInterval)
+	}
+
+	if strings.Join(cfg.ZoneNames, ",") != "example.com,example.org" {
+		t.Errorf("ZoneNames = %q; want [example.com example.org]", cfg.ZoneNames)
+	}
+
+	if cfg.IPProviderURL != "https://ip.example.com/raw" {
+		t.Errorf("IPProviderURL = %s; want https://ip.example.com/raw", cfg.IPProviderURL)
+	}
+}
+
+func TestNew_ReportsAllErrors(t *testing.T) {
+	t.Setenv("CHECK_INTERVAL", "daily")
+	t.Setenv("CHECK_JITTER", "150")
+	t.Setenv("DRY_RUN", "sometimes")
+	t.Setenv("IP_PROVIDER_URL", "ip.example.com")
+	t.Setenv("CONTROL_API_ADDR", "127.0.0.1:9091")
+	t.Setenv("DELETE_REMOVED_RECORDS", "true")
+
+	_, err := New("")
+	if err == nil {
+		t.Fatal("New() error = nil; want the configuration problems")
+	}
+
+	for _, want := range []string{
+		"CHECK_INTERVAL",
+		"DRY_RUN",
+		"IP_PROVIDER_URL",
+		"either an API token",
+		"no zone ids or zone names",
+		"check jitter",
+		"control api token",
+		"deleting the removed records requires creating the missing records",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("New() error = %v; want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestNew_SMTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantPort int
+		wantErr  string
+	}{
+		{
+			name:     "Defaults",
+			env:      map[string]string{},
+			wantPort: 587,
+		},
+		{
+			name:     "ImplicitTLSDefaultPort",
+			env:      map[string]string{"SMTP_TLS": "TLS"},
+			wantPort: 465,
+		},
+		{
+			name:     "CustomPort",
+			env:      map[string]string{"SMTP_TLS": "none", "SMTP_PORT": "2525", "SMTP_AUTH": "none", "SENDER_PASSWORD": ""},
+			wantPort: 2525,
+		},
+		{
+			name:    "InvalidModes",
+			env:     map[string]string{"SMTP_TLS": "ssl", "SMTP_AUTH": "cram-md5"},
+			wantErr: "smtp tls mode",
+		},
+		{
+			name:    "MissingPassword",
+			env:     map[string]string{"SENDER_PASSWORD": ""},
+			wantErr: "sender password is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("API_TOKEN", "testToken")
+			t.Setenv("ZONE_NAME", "example.com")
+			t.Setenv("SENDER_ADDRESS", "updater@example.com")
+			t.Setenv("SENDER_PASSWORD", "supersecret")
+			t.Setenv("RECEIVER_ADDRESS", "john@example.com, jane@example.com")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := New("")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if cfg.SMTPPort != tt.wantPort {
+				t.Errorf("SMTPPort = %d; want %d", cfg.SMTPPort, tt.wantPort)
+			}
+			if len(cfg.ReceiverAddress) != 2 || !cfg.EmailEnabled() {
+				t.Errorf("ReceiverAddress = %q; want 2 receivers and email enabled", cfg.ReceiverAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the content of the optional YAML configuration file.
+// Environment variables take precedence over the values set in the file.
+type File struct {
+	CheckInterval *int         `yaml:"check_interval"`
+	CheckJitter   *int         `yaml:"check_jitter"`
+	CreateMissing *bool        `yaml:"create_missing_records"`
+	DeleteRemoved *bool        `yaml:"delete_removed_records"`
+	DryRun        *bool        `yaml:"dry_run"`
+	IPQuorum      *int         `yaml:"ip_quorum"`
+	IPv4Enabled   *bool        `yaml:"ipv4_enabled"`
+	IPv6Enabled   *bool        `yaml:"ipv6_enabled"`
+	StateFile     string       `yaml:"state_file"`
+	IPProviders   []string     `yaml:"ip_providers"`
+	Zones         []ZoneConfig `yaml:"zones"`
+}
+
+// ZoneConfig describes a zone, identified by id or name, and the rules selecting its records.
+// A zone without rules uses the RECORD_ID and RECORD_NAME selectors.
+type ZoneConfig struct {
+	ID      string       `yaml:"id"`
+	Name    string       `yaml:"name"`
+	Records []RecordRule `yaml:"records"`
+}
+
+// RecordRule selects records by id or by name, and sets the options applied to them.
+// Names can be glob patterns, and names prefixed with ! exclude the matching records.
+type RecordRule struct {
+	Proxied *bool  `yaml:"proxied"`
+	ID      string `yaml:"id"`
+	Name    string `yaml:"name"`
+	TTL     int    `yaml:"ttl"`
+}
+
+// IsExclusion reports whether the rule excludes the records it matches
+func (r RecordRule) IsExclusion() bool {
+	return strings.HasPrefix(strings.TrimSpace(r.Name), "!")
+}
+
+// LoadFile reads and validates the configuration file
+func LoadFile(path string) (file *File, err error) {
+	zap.S().Infof("Loading configuration file %s", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file = &File{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// decode again as a node tree, to report the line of the invalid values
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if errs := file.validate(&root); len(errs) > 0 {
+		for i, err := range errs {
+			errs[i] = fmt.Errorf("%s:%w", path, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	return file, nil
+}
+
+// validate checks the zones and record rules, returning an error for every problem found
+func (f *File) validate(root *yaml.Node) (errs []error) {
+	zonesNode := mappingValue(documentNode(root), "zones")
+
+	for i, zone := range f.Zones {
+		zoneNode := sequenceItem(zonesNode, i)
+		if zone.ID == "" && zone.Name == "" {
+			errs = append(errs, lineError(zoneNode, "zone must have an id or a name"))
+		}
+
+		recordsNode := mappingValue(zoneNode, "records")
+		for j, rule := range zone.Records {
+			ruleNode := sequenceItem(recordsNode, j)
+			if rule.ID == "" && rule.Name == "" {
+				errs = append(errs, lineError(ruleNode, "record rule must have an id or a name"))
+			}
+
+			if rule.IsExclusion() && (rule.Proxied != nil || rule.TTL != 0) {
+				errs = append(errs, lineError(ruleNode, "exclusion rule %s can't set proxied or ttl", rule.Name))
+			}
+
+			if rule.TTL != 0 && rule.TTL != 1 && (rule.TTL < 60 || rule.TTL > 86400) {
+				errs = append(errs, lineError(mappingValue(ruleNode, "ttl"), "ttl must be 1 (automatic) or between 60 and 86400, got %d", rule.TTL))
+			}
+		}
+	}
+
+	return errs
+}
+
+// documentNode returns the top level mapping of a document node
+func documentNode(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+
+	return node
+}
+
+// mappingValue returns the value of the key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// sequenceItem returns the i-th item of a sequence node, or nil
+func sequenceItem(node *yaml.Node, i int) *yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+		return nil
+	}
+
+	return node.Content[i]
+}
+
+// lineError returns an error prefixed with the line of the node
+func lineError(node *yaml.Node, format string, args ...interface{}) error {
+	line := 0
+	if node != nil {
+		line = node.Line
+	}
+
+	return fmt.Errorf("%d: "+format, append([]interface{}{line}, args...)...)
+}
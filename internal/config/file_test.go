@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+// writeFile writes the content to a config file in a temporary directory and returns its path
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		expectedErrors []string
+		expectedZones  int
+	}{
+		{
+			name: "Valid",
+			content: `check_interval: 300
+zones:
+  - name: example.com
+    records:
+      - name: home.example.com
+        proxied: true
+        ttl: 120
+      - name: "*.lab.example.com"
+      - name: "!nas.lab.example.com"
+  - id: 372e67954025e0ba6aaa6d586b9e0b59
+`,
+			expectedZones: 2,
+		},
+		{
+			name:          "Empty",
+			content:       ``,
+			expectedZones: 0,
+		},
+		{
+			name: "UnknownField",
+			content: `zones:
+  - name: example.com
+    record:
+      - name: home.example.com
+`,
+			expectedErrors: []string{"line 3: field record not found"},
+		},
+		{
+			name: "WrongType",
+			content: `check_interval: often
+`,
+			expectedErrors: []string{"line 1: cannot unmarshal"},
+		},
+		{
+			name: "InvalidRules",
+			content: `zones:
+  - records:
+      - name: home.example.com
+  - name: example.com
+    records:
+      - proxied: true
+      - name: "!nas.example.com"
+        ttl: 120
+      - name: vpn.example.com
+        ttl: 30
+`,
+			expectedErrors: []string{
+				"config.yaml:2: zone must have an id or a name",
+				"config.yaml:6: record rule must have an id or a name",
+				"config.yaml:7: exclusion rule !nas.example.com can't set proxied or ttl",
+				"config.yaml:10: ttl must be 1 (automatic) or between 60 and 86400, got 30",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := LoadFile(writeFile(t, tt.content))
+
+			if len(tt.expectedErrors) == 0 {
+				if err != nil {
+					t.Fatalf("LoadFile() error = %v", err)
+				}
+				if len(file.Zones) != tt.expectedZones {
+					t.Errorf("LoadFile() = %d zones; want %d", len(file.Zones), tt.expectedZones)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("LoadFile() error = nil; want an error")
+			}
+
+			for _, expected := range tt.expectedErrors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("LoadFile() error = %v; want it to contain %q", err, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_WithFile(t *testing.T) {
+	path := writeFile(t, `check_interval: 300
+check_jitter: 5
+dry_run: true
+zones:
+  - name: example.com
+    records:
+      - name: home.example.com
+        ttl: 120
+  - id: 372e67954025e0ba6aaa6d586b9e0b59
+`)
+
+	t.Setenv("API_TOKEN", "testToken")
+	t.Setenv("CHECK_INTERVAL", "600")
+	t.Setenv("ZONE_NAME", "example.org")
+
+	cfg, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if cfg.CheckInterval != 600*time.Second {
+		t.Errorf("CheckInterval = %v; want the environment value 10m", cfg.CheckInterval)
+	}
+
+	if cfg.CheckJitter != 5 || !cfg.DryRun {
+		t.Errorf("CheckJitter = %d, DryRun = %t; want the file values 5 and true", cfg.CheckJitter, cfg.DryRun)
+	}
+
+	if strings.Join(cfg.ZoneNames, ",") != "example.org,example.com" {
+		t.Errorf("ZoneNames = %v; want [example.org example.com]", cfg.ZoneNames)
+	}
+
+	if strings.Join(cfg.ZoneIDs, ",") != "372e67954025e0ba6aaa6d586b9e0b59" {
+		t.Errorf("ZoneIDs = %v; want [372e67954025e0ba6aaa6d586b9e0b59]", cfg.ZoneIDs)
+	}
+
+	if len(cfg.Zones) != 2 || cfg.Zones[0].Records[0].TTL != 120 {
+		t.Errorf("Zones = %+v; want the zones of the file", cfg.Zones)
+	}
+}
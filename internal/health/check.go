@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/server"
+)
+
+// Check queries the /healthz endpoint of the instance listening on addr, as used by the
+// healthcheck subcommand. A wildcard listening address is reached on localhost, and a
+// Unix socket address (unix:/path) is dialed directly.
+func Check(ctx context.Context, addr string) error {
+	client := http.DefaultClient
+	baseURL := "http://localhost"
+
+	if path, ok := strings.CutPrefix(addr, server.UnixPrefix); ok {
+		client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}}
+	} else {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid HTTP address %q: %w", addr, err)
+		}
+
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			host = "127.0.0.1"
+		}
+		baseURL = "http://" + net.JoinHostPort(host, port)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var body status
+		_ = json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&body)
+		return fmt.Errorf("unhealthy (HTTP status code %d): %s", res.StatusCode, body.Error)
+	}
+
+	return nil
+}
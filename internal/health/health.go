@@ -0,0 +1,122 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health tracks the liveness and the readiness of the updater
+type Health struct {
+	now         func() time.Time
+	lastCheck   time.Time
+	lastSyncErr error
+	started     time.Time
+	// MaxCheckAge is how old the last ip check can be before the process is considered stuck
+	MaxCheckAge time.Duration
+	records     int
+	zones       int
+	loaded      bool
+	synced      bool
+	mu          sync.Mutex
+}
+
+// New creates a new Health. The process is live as long as the ip is checked at least every maxCheckAge.
+func New(maxCheckAge time.Duration) *Health {
+	return &Health{
+		now:         time.Now,
+		started:     time.Now(),
+		MaxCheckAge: maxCheckAge,
+	}
+}
+
+// SetLoaded records that the zones and records to update were loaded
+func (h *Health) SetLoaded(zones, records int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.loaded = true
+	h.zones = zones
+	h.records = records
+}
+
+// RecordSync records the outcome of a sync, i.e. an ip check and the resulting updates
+func (h *Health) RecordSync(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastCheck = h.now()
+	h.lastSyncErr = err
+	h.synced = true
+}
+
+// Live returns an error when the ip wasn't checked for longer than MaxCheckAge
+func (h *Health) Live() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last := h.lastCheck
+	if !h.synced {
+		last = h.started
+	}
+
+	if age := h.now().Sub(last); age > h.MaxCheckAge {
+		return fmt.Errorf("last ip check was %s ago, more than %s", age.Round(time.Second), h.MaxCheckAge)
+	}
+
+	return nil
+}
+
+// Ready returns an error until the zones and records are loaded and a sync succeeded,
+// or when the last sync failed
+func (h *Health) Ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case !h.loaded:
+		return errors.New("zones and records not loaded yet")
+	case h.records == 0:
+		return errors.New("no records to update")
+	case !h.synced:
+		return errors.New("first sync not completed yet")
+	case h.lastSyncErr != nil:
+		return fmt.Errorf("last sync failed: %w", h.lastSyncErr)
+	}
+
+	return nil
+}
+
+// status is the body of the health endpoints
+type status struct {
+	Error  string `json:"error,omitempty"`
+	Status string `json:"status"`
+}
+
+// LiveHandler serves the liveness, for /healthz
+func (h *Health) LiveHandler() http.Handler {
+	return handler(h.Live)
+}
+
+// ReadyHandler serves the readiness, for /readyz
+func (h *Health) ReadyHandler() http.Handler {
+	return handler(h.Ready)
+}
+
+// handler responds 200 when the check passes and 503 with the error otherwise
+func handler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(status{Error: err.Error(), Status: "error"})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(status{Status: "ok"})
+	})
+}
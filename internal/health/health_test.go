@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := New(time.Hour)
+	h.now = func() time.Time { return now }
+	h.started = now
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		action    func()
+		wantLive  string
+		wantReady string
+	}{
+		{name: "Starting", wantReady: "not loaded"},
+		{name: "NoRecords", action: func() { h.SetLoaded(1, 0) }, wantReady: "no records"},
+		{name: "Loaded", action: func() { h.SetLoaded(1, 2) }, wantReady: "first sync"},
+		{name: "Synced", advance: time.Minute, action: func() { h.RecordSync(nil) }},
+		{name: "SyncFailed", advance: time.Minute, action: func() { h.RecordSync(errors.New("timeout")) }, wantReady: "last sync failed: timeout"},
+		{name: "SyncRecovered", advance: time.Minute, action: func() { h.RecordSync(nil) }},
+		{name: "Stuck", advance: 2 * time.Hour, wantLive: "more than 1h0m0s"},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			now = now.Add(step.advance)
+			if step.action != nil {
+				step.action()
+			}
+
+			checkErr(t, "Live()", h.Live(), step.wantLive)
+			checkErr(t, "Ready()", h.Ready(), step.wantReady)
+		})
+	}
+}
+
+// checkErr fails the test when err doesn't contain want, or isn't nil when want is empty
+func checkErr(t *testing.T, name string, err error, want string) {
+	t.Helper()
+
+	if want == "" && err != nil {
+		t.Errorf("%s error = %v; want nil", name, err)
+	}
+	if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+		t.Errorf("%s error = %v; want %q", name, err, want)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	h := New(time.Hour)
+
+	rec := httptest.NewRecorder()
+	h.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"status":"ok"}` {
+		t.Errorf("GET /healthz = %d %s; want 200 ok", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "not loaded") {
+		t.Errorf("GET /readyz = %d %s; want 503 with the reason", rec.Code, rec.Body)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	h := New(time.Hour)
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", h.LiveHandler())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	if err := Check(context.Background(), ":"+port); err != nil {
+		t.Errorf("Check() error = %v; want a healthy instance reached on localhost", err)
+	}
+
+	h.mu.Lock()
+	h.MaxCheckAge = -time.Second
+	h.mu.Unlock()
+	if err := Check(context.Background(), "0.0.0.0:"+port); err == nil || !strings.Contains(err.Error(), "last ip check") {
+		t.Errorf("Check() error = %v; want the reason of the failure", err)
+	}
+
+	if err := Check(context.Background(), "no-port"); err == nil {
+		t.Error("Check() error = nil; want an invalid address error")
+	}
+}
+
+func TestCheck_Unix(t *testing.T) {
+	h := New(time.Hour)
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", h.LiveHandler())
+
+	path := filepath.Join(t.TempDir(), "http.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	if err := Check(context.Background(), "unix:"+path); err != nil {
+		t.Errorf("Check() error = %v; want a healthy instance reached on the socket", err)
+	}
+}
@@ -0,0 +1,211 @@
+package ipsource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Provider discovers the current public ip address
+type Provider interface {
+	Name() string
+	GetIP(ctx context.Context) (ip string, err error)
+}
+
+// Family is the ip address family a provider discovers
+type Family int
+
+const (
+	IPv4 Family = 4
+	IPv6 Family = 6
+)
+
+// String returns the name of the family
+func (f Family) String() string {
+	if f == IPv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// HTTPProvider is a Provider that reads the ip address from an HTTP endpoint
+type HTTPProvider struct {
+	HTTPClient HTTPClient
+	Extract    func(body []byte) (ip string, err error)
+	ProvName   string
+	URL        string
+	Family     Family
+}
+
+// Name returns the name of the provider
+func (p *HTTPProvider) Name() string {
+	return p.ProvName
+}
+
+// GetIP fetches the provider URL and extracts the ip address from the response body
+func (p *HTTPProvider) GetIP(ctx context.Context) (ip string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status code %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+
+	ip, err = p.Extract(body)
+	if err != nil {
+		return "", err
+	}
+
+	return parseIP(ip, p.Family)
+}
+
+// NewIpify creates a provider for https://www.ipify.org
+func NewIpify(family Family, client HTTPClient) *HTTPProvider {
+	url := "https://api.ipify.org"
+	if family == IPv6 {
+		url = "https://api6.ipify.org"
+	}
+
+	return &HTTPProvider{
+		HTTPClient: client,
+		Extract:    extractPlainText,
+		ProvName:   "ipify",
+		URL:        url,
+		Family:     family,
+	}
+}
+
+// NewIcanhazip creates a provider for https://icanhazip.com
+func NewIcanhazip(family Family, client HTTPClient) *HTTPProvider {
+	url := "https://ipv4.icanhazip.com"
+	if family == IPv6 {
+		url = "https://ipv6.icanhazip.com"
+	}
+
+	return &HTTPProvider{
+		HTTPClient: client,
+		Extract:    extractPlainText,
+		ProvName:   "icanhazip",
+		URL:        url,
+		Family:     family,
+	}
+}
+
+// NewIfconfigCo creates a provider for https://ifconfig.co.
+// The service is dual stack, so the family is enforced by the client.
+func NewIfconfigCo(family Family, client HTTPClient) *HTTPProvider {
+	return &HTTPProvider{
+		HTTPClient: client,
+		Extract:    extractPlainText,
+		ProvName:   "ifconfig.co",
+		URL:        "https://ifconfig.co/ip",
+		Family:     family,
+	}
+}
+
+// NewCloudflareTrace creates a provider for the Cloudflare cdn-cgi/trace endpoint
+func NewCloudflareTrace(family Family, client HTTPClient) *HTTPProvider {
+	url := "https://1.1.1.1/cdn-cgi/trace"
+	if family == IPv6 {
+		url = "https://[2606:4700:4700::1111]/cdn-cgi/trace"
+	}
+
+	return &HTTPProvider{
+		HTTPClient: client,
+		Extract:    extractTraceIP,
+		ProvName:   "cloudflare",
+		URL:        url,
+		Family:     family,
+	}
+}
+
+// NewCustom creates a provider for a user defined URL. The ip address is extracted
+// with the given regular expression, using the first capture group if there is one.
+// An empty expression means the whole body is the ip address.
+func NewCustom(family Family, url, expr string, client HTTPClient) (*HTTPProvider, error) {
+	if url == "" {
+		return nil, errors.New("custom ip provider requires an URL")
+	}
+
+	provider := &HTTPProvider{
+		HTTPClient: client,
+		Extract:    extractPlainText,
+		ProvName:   "custom",
+		URL:        url,
+		Family:     family,
+	}
+
+	if expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom ip provider regex: %w", err)
+		}
+		provider.Extract = func(body []byte) (string, error) {
+			match := re.FindSubmatch(body)
+			if match == nil {
+				return "", errors.New("regex did not match the response body")
+			}
+			if len(match) > 1 {
+				return string(match[1]), nil
+			}
+			return string(match[0]), nil
+		}
+	}
+
+	return provider, nil
+}
+
+// extractPlainText treats the whole body as the ip address
+func extractPlainText(body []byte) (string, error) {
+	return string(bytes.TrimSpace(body)), nil
+}
+
+// extractTraceIP reads the ip address from the "ip=" line of a cdn-cgi/trace response
+func extractTraceIP(body []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if ip, ok := strings.CutPrefix(scanner.Text(), "ip="); ok {
+			return ip, nil
+		}
+	}
+
+	return "", errors.New("no ip field in trace response")
+}
+
+// parseIP validates the ip address and its family, and returns it in its canonical form
+func parseIP(raw string, family Family) (string, error) {
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if ip == nil {
+		return "", fmt.Errorf("invalid ip address %q", raw)
+	}
+
+	if isIPv4 := ip.To4() != nil; isIPv4 != (family != IPv6) {
+		return "", fmt.Errorf("ip address %s is not an %s address", ip, family)
+	}
+
+	return ip.String(), nil
+}
@@ -0,0 +1,161 @@
+package ipsource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+func TestHTTPProvider_GetIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		newFunc    func(url string) (*HTTPProvider, error)
+		response   string
+		statusCode int
+		expectedIP string
+		wantErr    bool
+	}{
+		{
+			name: "PlainText",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewIpify(IPv4, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "203.0.113.10\n",
+			statusCode: http.StatusOK,
+			expectedIP: "203.0.113.10",
+			wantErr:    false,
+		},
+		{
+			name: "CloudflareTrace",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewCloudflareTrace(IPv4, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "fl=123\nh=www.cloudflare.com\nip=203.0.113.11\nts=1700000000.000\n",
+			statusCode: http.StatusOK,
+			expectedIP: "203.0.113.11",
+			wantErr:    false,
+		},
+		{
+			name: "CloudflareTraceMissingIP",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewCloudflareTrace(IPv4, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "fl=123\nh=www.cloudflare.com\n",
+			statusCode: http.StatusOK,
+			wantErr:    true,
+		},
+		{
+			name: "CustomRegexWithGroup",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				return NewCustom(IPv4, url, `"ip":"([^"]+)"`, http.DefaultClient)
+			},
+			response:   `{"ip":"203.0.113.12","country":"IT"}`,
+			statusCode: http.StatusOK,
+			expectedIP: "203.0.113.12",
+			wantErr:    false,
+		},
+		{
+			name: "CustomRegexNoMatch",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				return NewCustom(IPv4, url, `address: (\S+)`, http.DefaultClient)
+			},
+			response:   `{"ip":"203.0.113.12"}`,
+			statusCode: http.StatusOK,
+			wantErr:    true,
+		},
+		{
+			name: "InvalidIP",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewIcanhazip(IPv4, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "<html>rate limited</html>",
+			statusCode: http.StatusOK,
+			wantErr:    true,
+		},
+		{
+			name: "IPv6",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewIpify(IPv6, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "2001:DB8::1\n",
+			statusCode: http.StatusOK,
+			expectedIP: "2001:db8::1",
+			wantErr:    false,
+		},
+		{
+			name: "WrongFamily",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewIpify(IPv6, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "203.0.113.10",
+			statusCode: http.StatusOK,
+			wantErr:    true,
+		},
+		{
+			name: "BadStatusCode",
+			newFunc: func(url string) (*HTTPProvider, error) {
+				p := NewIfconfigCo(IPv4, http.DefaultClient)
+				p.URL = url
+				return p, nil
+			},
+			response:   "203.0.113.10",
+			statusCode: http.StatusServiceUnavailable,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			provider, err := tt.newFunc(server.URL)
+			if err != nil {
+				t.Fatalf("failed to create provider: %v", err)
+			}
+
+			ip, err := provider.GetIP(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if ip != tt.expectedIP {
+				t.Errorf("GetIP() = %s; want %s", ip, tt.expectedIP)
+			}
+		})
+	}
+}
+
+func TestNewCustom(t *testing.T) {
+	if _, err := NewCustom(IPv4, "", "", http.DefaultClient); err == nil {
+		t.Error("NewCustom(IPv4, ) with empty URL should fail")
+	}
+
+	if _, err := NewCustom(IPv4, "http://localhost", "([", http.DefaultClient); err == nil {
+		t.Error("NewCustom(IPv4, ) with invalid regex should fail")
+	}
+}
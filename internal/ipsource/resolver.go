@@ -0,0 +1,152 @@
+package ipsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Resolver queries several providers and returns the ip address they agree on
+type Resolver struct {
+	Providers []Provider
+	Quorum    int
+	Family    Family
+}
+
+// New creates a new Resolver for the given family with the providers listed in the config
+func New(cfg *config.Config, family Family) (resolver *Resolver, err error) {
+	zap.S().Debugf("Creating %s resolver", family)
+	client := newHTTPClient(family)
+
+	providers := make([]Provider, 0, len(cfg.IPProviders))
+	for _, name := range cfg.IPProviders {
+		var provider Provider
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "ipify":
+			provider = NewIpify(family, client)
+		case "icanhazip":
+			provider = NewIcanhazip(family, client)
+		case "ifconfig.co", "ifconfig":
+			provider = NewIfconfigCo(family, client)
+		case "cloudflare":
+			provider = NewCloudflareTrace(family, client)
+		case "custom":
+			provider, err = NewCustom(family, cfg.IPProviderURL, cfg.IPProviderRegex, client)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown ip provider %s", name)
+		}
+		providers = append(providers, provider)
+	}
+
+	resolver, err = NewResolver(providers, cfg.IPQuorum)
+	if err != nil {
+		return nil, err
+	}
+	resolver.Family = family
+
+	return resolver, nil
+}
+
+// newHTTPClient creates an HTTP client that only connects over the given family,
+// so dual stack providers report the address of that family
+func newHTTPClient(family Family) *http.Client {
+	network := "tcp4"
+	if family == IPv6 {
+		network = "tcp6"
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+}
+
+// NewResolver creates a new Resolver. A quorum of 0 means a simple majority of the providers.
+func NewResolver(providers []Provider, quorum int) (resolver *Resolver, err error) {
+	if len(providers) == 0 {
+		return nil, errors.New("no ip providers configured")
+	}
+
+	if quorum <= 0 {
+		quorum = len(providers)/2 + 1
+	}
+
+	if quorum > len(providers) {
+		return nil, fmt.Errorf("ip quorum %d is greater than the number of providers (%d)", quorum, len(providers))
+	}
+
+	return &Resolver{
+		Providers: providers,
+		Quorum:    quorum,
+		Family:    IPv4,
+	}, nil
+}
+
+// Resolve queries the providers in order until Quorum of them agree on the same ip address.
+// Failing providers are skipped, so the next ones act as a fallback.
+func (r *Resolver) Resolve(ctx context.Context) (ip string, err error) {
+	defer func() {
+		metrics.IPChecks.Inc(r.Family.String(), metrics.Result(err))
+	}()
+
+	votes := make(map[string]int)
+	var errs []error
+
+	for i, provider := range r.Providers {
+		// stop early when the remaining providers can't reach the quorum anymore
+		if best(votes)+len(r.Providers)-i < r.Quorum {
+			break
+		}
+
+		providerIP, err := provider.GetIP(ctx)
+		if err != nil {
+			zap.S().Warnf("Ip provider %s failed: %v", provider.Name(), err)
+			metrics.ProviderFailures.Inc(provider.Name(), r.Family.String())
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		zap.S().Debugf("Ip provider %s returned %s", provider.Name(), providerIP)
+		votes[providerIP]++
+		if votes[providerIP] >= r.Quorum {
+			return providerIP, nil
+		}
+	}
+
+	if len(votes) > 0 {
+		errs = append(errs, fmt.Errorf("no ip address reached the quorum of %d: %v", r.Quorum, votes))
+	}
+
+	return "", fmt.Errorf("unable to resolve current %s address: %w", r.Family, errors.Join(errs...))
+}
+
+// best returns the highest number of votes received by a single ip address
+func best(votes map[string]int) (count int) {
+	for _, v := range votes {
+		if v > count {
+			count = v
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,123 @@
+package ipsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockProvider struct {
+	err   error
+	ip    string
+	calls int
+}
+
+func (m *mockProvider) Name() string {
+	return "mock"
+}
+
+func (m *mockProvider) GetIP(_ context.Context) (string, error) {
+	m.calls++
+	return m.ip, m.err
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	errProvider := errors.New("provider down")
+
+	tests := []struct {
+		name          string
+		providers     []*mockProvider
+		quorum        int
+		expectedIP    string
+		expectedCalls []int
+		wantErr       bool
+	}{
+		{
+			name:          "SingleProvider",
+			providers:     []*mockProvider{{ip: "203.0.113.1"}},
+			quorum:        1,
+			expectedIP:    "203.0.113.1",
+			expectedCalls: []int{1},
+			wantErr:       false,
+		},
+		{
+			name:          "FallbackOnFailure",
+			providers:     []*mockProvider{{err: errProvider}, {ip: "203.0.113.1"}},
+			quorum:        1,
+			expectedIP:    "203.0.113.1",
+			expectedCalls: []int{1, 1},
+			wantErr:       false,
+		},
+		{
+			name:          "StopsOnceQuorumReached",
+			providers:     []*mockProvider{{ip: "203.0.113.1"}, {ip: "203.0.113.1"}, {ip: "203.0.113.1"}},
+			quorum:        2,
+			expectedIP:    "203.0.113.1",
+			expectedCalls: []int{1, 1, 0},
+			wantErr:       false,
+		},
+		{
+			name:          "MajorityWithDisagreement",
+			providers:     []*mockProvider{{ip: "203.0.113.1"}, {ip: "203.0.113.2"}, {ip: "203.0.113.2"}},
+			quorum:        0,
+			expectedIP:    "203.0.113.2",
+			expectedCalls: []int{1, 1, 1},
+			wantErr:       false,
+		},
+		{
+			name:          "NoConsensus",
+			providers:     []*mockProvider{{ip: "203.0.113.1"}, {ip: "203.0.113.2"}, {err: errProvider}},
+			quorum:        2,
+			expectedIP:    "",
+			expectedCalls: []int{1, 1, 1},
+			wantErr:       true,
+		},
+		{
+			name:          "QuorumUnreachable",
+			providers:     []*mockProvider{{err: errProvider}, {err: errProvider}, {ip: "203.0.113.1"}},
+			quorum:        2,
+			expectedIP:    "",
+			expectedCalls: []int{1, 1, 0},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]Provider, len(tt.providers))
+			for i, p := range tt.providers {
+				providers[i] = p
+			}
+
+			resolver, err := NewResolver(providers, tt.quorum)
+			if err != nil {
+				t.Fatalf("NewResolver() error = %v", err)
+			}
+
+			ip, err := resolver.Resolve(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if ip != tt.expectedIP {
+				t.Errorf("Resolve() = %s; want %s", ip, tt.expectedIP)
+			}
+
+			for i, p := range tt.providers {
+				if p.calls != tt.expectedCalls[i] {
+					t.Errorf("provider %d called %d times; want %d", i, p.calls, tt.expectedCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	if _, err := NewResolver(nil, 1); err == nil {
+		t.Error("NewResolver() without providers should fail")
+	}
+
+	if _, err := NewResolver([]Provider{&mockProvider{}}, 2); err == nil {
+		t.Error("NewResolver() with quorum greater than providers should fail")
+	}
+}
@@ -9,8 +9,13 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-// New creates a new logger
+// New creates a new logger writing to stdout
 func New(logLevelEnv string) *zap.SugaredLogger {
+	return NewWithOutput(logLevelEnv, os.Stdout)
+}
+
+// NewWithOutput creates a new logger writing to out, e.g. stderr when stdout is the output of a command
+func NewWithOutput(logLevelEnv string, out zapcore.WriteSyncer) *zap.SugaredLogger {
 	logLevel := os.Getenv(logLevelEnv)
 	if logLevel == "" {
 		logLevel = "info"
@@ -24,22 +29,22 @@ func New(logLevelEnv string) *zap.SugaredLogger {
 	switch logLevel {
 	case "debug":
 		core = ecszap.NewCore(
-			encoderConfig, os.Stdout, zap.DebugLevel)
+			encoderConfig, out, zap.DebugLevel)
 	case "info":
 		core = ecszap.NewCore(
-			encoderConfig, os.Stdout, zap.InfoLevel)
+			encoderConfig, out, zap.InfoLevel)
 	case "warn":
 		core = ecszap.NewCore(
-			encoderConfig, os.Stdout, zap.WarnLevel)
+			encoderConfig, out, zap.WarnLevel)
 	case "error":
 		core = ecszap.NewCore(
-			encoderConfig, os.Stdout, zap.ErrorLevel)
+			encoderConfig, out, zap.ErrorLevel)
 	case "fatal":
 		core = ecszap.NewCore(
-			encoderConfig, os.Stdout, zap.FatalLevel)
+			encoderConfig, out, zap.FatalLevel)
 	default:
 		core = ecszap.NewCore(
-			encoderConfig, os.Stdout, zap.InfoLevel)
+			encoderConfig, out, zap.InfoLevel)
 	}
 
 	logger := zap.New(core, zap.AddCaller())
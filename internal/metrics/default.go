@@ -0,0 +1,44 @@
+package metrics
+
+import "net/http"
+
+// Default is the Registry of the metrics of the updater
+var Default = NewRegistry()
+
+var (
+	// IPChecks counts the public ip checks by family and result (success or failure)
+	IPChecks = Default.NewCounter("cfdns_updater_ip_checks_total",
+		"Public IP address checks by address family and result.", "family", "result")
+	// ProviderFailures counts the failed queries by ip provider and family
+	ProviderFailures = Default.NewCounter("cfdns_updater_ip_provider_failures_total",
+		"Failed queries to the IP providers by provider and address family.", "provider", "family")
+	// APIRequests counts the Cloudflare API calls by endpoint, method and status code
+	APIRequests = Default.NewCounter("cfdns_updater_cloudflare_requests_total",
+		"Cloudflare API requests by endpoint, method and HTTP status code (error when no response was received).", "endpoint", "method", "code")
+	// RecordUpdates counts the record updates by result (success, failure, or deleted when the record no longer exists)
+	RecordUpdates = Default.NewCounter("cfdns_updater_record_updates_total",
+		"DNS record updates by result.", "result")
+	// Notifications counts the notifications sent by channel and result (success or failure)
+	Notifications = Default.NewCounter("cfdns_updater_notifications_total",
+		"Notifications sent by channel and result.", "channel", "result")
+	// IPChanged is the time the records of a family were last updated to a new ip
+	IPChanged = Default.NewGauge("cfdns_updater_ip_change_timestamp_seconds",
+		"Unix time the records were last updated to a new IP address, by address family.", "family")
+	// LastSuccessfulSync is the time of the last sync without errors
+	LastSuccessfulSync = Default.NewGauge("cfdns_updater_last_successful_sync_timestamp_seconds",
+		"Unix time of the last sync that completed without errors.")
+)
+
+// Handler returns an HTTP handler serving the Default metrics
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// Result returns the result label of an error
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
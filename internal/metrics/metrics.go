@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry holds a set of metrics and writes them in the Prometheus text format
+type Registry struct {
+	metrics []*metric
+	mu      sync.Mutex
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// metric is a counter or a gauge with its values by label values
+type metric struct {
+	values     map[string]float64
+	help       string
+	kind       string
+	name       string
+	labelNames []string
+	mu         sync.Mutex
+}
+
+// Counter is a metric that only goes up
+type Counter struct {
+	m *metric
+}
+
+// Gauge is a metric that can be set to any value
+type Gauge struct {
+	m *metric
+}
+
+// NewCounter registers a new Counter with the given label names
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	return &Counter{m: r.register(name, help, "counter", labelNames)}
+}
+
+// NewGauge registers a new Gauge with the given label names
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	return &Gauge{m: r.register(name, help, "gauge", labelNames)}
+}
+
+func (r *Registry) register(name, help, kind string, labelNames []string) *metric {
+	m := &metric{
+		values:     make(map[string]float64),
+		help:       help,
+		kind:       kind,
+		name:       name,
+		labelNames: labelNames,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+
+	return m
+}
+
+// Inc increments the counter of the label values by 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter of the label values by v, which must not be negative
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+
+	c.m.update(labelValues, func(old float64) float64 { return old + v })
+}
+
+// Value returns the current value of the counter of the label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.m.value(labelValues)
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.m.update(labelValues, func(float64) float64 { return v })
+}
+
+// SetToTime sets the gauge of the label values to the unix timestamp of t
+func (g *Gauge) SetToTime(t time.Time, labelValues ...string) {
+	g.Set(float64(t.UnixNano())/1e9, labelValues...)
+}
+
+// Value returns the current value of the gauge of the label values
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.m.value(labelValues)
+}
+
+func (m *metric) update(labelValues []string, f func(float64) float64) {
+	if len(labelValues) != len(m.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", m.name, len(m.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = f(m.values[key])
+}
+
+func (m *metric) value(labelValues []string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.values[strings.Join(labelValues, "\xff")]
+}
+
+// Write writes all the metrics in the Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]*metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+func (m *metric) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, strings.ReplaceAll(m.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+	// a metric without labels always has a value
+	if len(m.labelNames) == 0 {
+		fmt.Fprintf(w, "%s %s\n", m.name, formatValue(m.values[""]))
+		return
+	}
+
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		labelValues := strings.Split(key, "\xff")
+		labels := make([]string, len(m.labelNames))
+		for i, name := range m.labelNames {
+			labels[i] = name + `="` + escapeLabel(labelValues[i]) + `"`
+		}
+		fmt.Fprintf(w, "%s{%s} %s\n", m.name, strings.Join(labels, ","), formatValue(m.values[key]))
+	}
+}
+
+// Handler returns an HTTP handler serving the metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.Write(w)
+	})
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("test_requests_total", "Requests by endpoint and code.", "endpoint", "code")
+	lastSync := r.NewGauge("test_last_sync_timestamp_seconds", "Last sync.")
+	changed := r.NewGauge("test_changed_timestamp_seconds", "Changes by family.", "family")
+
+	requests.Inc("/zones", "200")
+	requests.Add(2, "/zones", "200")
+	requests.Inc(`/weird "path"`+"\n", "error")
+	lastSync.SetToTime(time.Unix(1767323045, 500000000))
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := `# HELP test_requests_total Requests by endpoint and code.
+# TYPE test_requests_total counter
+test_requests_total{endpoint="/weird \"path\"\n",code="error"} 1
+test_requests_total{endpoint="/zones",code="200"} 3
+# HELP test_last_sync_timestamp_seconds Last sync.
+# TYPE test_last_sync_timestamp_seconds gauge
+test_last_sync_timestamp_seconds 1.7673230455e+09
+# HELP test_changed_timestamp_seconds Changes by family.
+# TYPE test_changed_timestamp_seconds gauge
+`
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if got := requests.Value("/zones", "200"); got != 3 {
+		t.Errorf("Value() = %v; want 3", got)
+	}
+
+	changed.Set(1, "IPv4")
+	if got := changed.Value("IPv4"); got != 1 {
+		t.Errorf("Value() = %v; want 1", got)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %s; want the Prometheus text format", ct)
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("test_total 1\n")) {
+		t.Errorf("body = %s; want test_total 1", rec.Body.String())
+	}
+}
+
+func TestCounter_WrongLabels(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc() with missing label values didn't panic")
+		}
+	}()
+
+	NewRegistry().NewCounter("test_total", "Test.", "family").Inc()
+}
@@ -0,0 +1,88 @@
+package notifier
+
+import (
+	"context"
+)
+
+// Slack is a Notifier posting to a Slack incoming webhook
+type Slack struct {
+	HTTPClient HTTPClient
+	Templates  *Templates
+	URL        string
+}
+
+// Name returns the name of the channel
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Notify posts the event to the Slack webhook
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	msg, err := s.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{"text": "*" + msg.Subject + "*\n" + msg.Text}
+
+	return postJSON(ctx, s.HTTPClient, s.URL, payload, nil)
+}
+
+// Discord is a Notifier posting to a Discord webhook
+type Discord struct {
+	HTTPClient HTTPClient
+	Templates  *Templates
+	URL        string
+}
+
+// Name returns the name of the channel
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+// Notify posts the event to the Discord webhook
+func (d *Discord) Notify(ctx context.Context, event Event) error {
+	msg, err := d.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{"content": "**" + msg.Subject + "**\n" + msg.Text}
+
+	return postJSON(ctx, d.HTTPClient, d.URL, payload, nil)
+}
+
+// Telegram is a Notifier sending messages through a Telegram bot
+type Telegram struct {
+	HTTPClient HTTPClient
+	Templates  *Templates
+	BotToken   string
+	ChatID     string
+	// APIURL overrides the Telegram Bot API URL
+	APIURL string
+}
+
+// Name returns the name of the channel
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+// Notify sends the event to the chat
+func (t *Telegram) Notify(ctx context.Context, event Event) error {
+	msg, err := t.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	apiURL := t.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.telegram.org"
+	}
+
+	payload := map[string]string{
+		"chat_id": t.ChatID,
+		"text":    msg.Subject + "\n\n" + msg.Text,
+	}
+
+	return postJSON(ctx, t.HTTPClient, apiURL+"/bot"+t.BotToken+"/sendMessage", payload, nil)
+}
@@ -0,0 +1,262 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+)
+
+// Email sends the notifications through an SMTP server
+type Email struct {
+	// TLSConfig overrides the TLS configuration, mostly useful to trust a private CA
+	TLSConfig         *tls.Config
+	Templates         *Templates
+	Auth              string
+	SenderAddress     string
+	SenderName        string
+	SenderPassword    string
+	SMTPHost          string
+	TLSMode           string
+	ReceiverAddresses []string
+	SMTPPort          int
+	Timeout           time.Duration
+}
+
+// Name returns the name of the channel
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Notify sends the event by email
+func (e *Email) Notify(_ context.Context, event Event) error {
+	msg, err := e.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	return e.Send(msg)
+}
+
+// Send sends the message to all the receivers, as a multipart email when it has an HTML version
+func (e *Email) Send(msg Message) (err error) {
+	data, err := e.message(msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := e.dial()
+	if err != nil {
+		return fmt.Errorf("unable to connect to the SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if e.TLSMode == config.SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("the SMTP server doesn't support STARTTLS")
+		}
+		if err = client.StartTLS(e.tlsConfig()); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if auth := e.auth(); auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("the SMTP server doesn't support authentication")
+		}
+		if err = client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err = client.Mail(e.SenderAddress); err != nil {
+		return err
+	}
+	for _, receiver := range e.ReceiverAddresses {
+		if err = client.Rcpt(receiver); err != nil {
+			return fmt.Errorf("receiver %s rejected: %w", receiver, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the SMTP server, over TLS when the implicit mode is used
+func (e *Email) dial() (*smtp.Client, error) {
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	addr := net.JoinHostPort(e.SMTPHost, strconv.Itoa(e.SMTPPort))
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if e.TLSMode == config.SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, e.tlsConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// bound the whole conversation, so a stuck server doesn't block the notification forever
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, e.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// tlsConfig returns the TLS configuration used to verify the server
+func (e *Email) tlsConfig() *tls.Config {
+	if e.TLSConfig != nil {
+		return e.TLSConfig
+	}
+
+	return &tls.Config{ServerName: e.SMTPHost, MinVersion: tls.VersionTLS12}
+}
+
+// auth returns the authentication mechanism, or nil when authentication is disabled
+func (e *Email) auth() smtp.Auth {
+	switch e.Auth {
+	case config.SMTPAuthNone:
+		return nil
+	case config.SMTPAuthLogin:
+		return &loginAuth{username: e.SenderAddress, password: e.SenderPassword, host: e.SMTPHost}
+	default:
+		return smtp.PlainAuth("", e.SenderAddress, e.SenderPassword, e.SMTPHost)
+	}
+}
+
+// message builds the headers and the body of the email. The text and HTML versions
+// are sent as the alternatives of a multipart message.
+func (e *Email) message(msg Message) ([]byte, error) {
+	from := mail.Address{Name: e.SenderName, Address: e.SenderAddress}
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + strings.Join(e.ReceiverAddresses, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	buf.WriteString("Content-Type: multipart/alternative; boundary=" + parts.Boundary() + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes the body encoded as quoted-printable, so long lines and
+// non ASCII characters survive the SMTP transport
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+// loginAuth implements the LOGIN authentication mechanism, still required by some servers
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+// Start begins the authentication, refusing to send the credentials over an unencrypted connection
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+// Next answers the username and password challenges of the server
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+// isLocalhost reports whether the host is the local machine, where credentials can be sent in clear
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
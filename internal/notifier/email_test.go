@@ -0,0 +1,374 @@
+package notifier
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+// smtpServer is a minimal in-process SMTP server recording what it receives
+type smtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	implicit  bool
+	startTLS  bool
+	password  string
+
+	mu        sync.Mutex
+	mechanism string
+	username  string
+	from      string
+	receivers []string
+	data      string
+	encrypted bool
+	delivered bool
+}
+
+// newSMTPServer starts an SMTP server on a random local port. The certificate is the
+// httptest one, valid for 127.0.0.1, and the returned config trusts it.
+func newSMTPServer(t *testing.T, implicit, startTLS bool) (*smtpServer, *tls.Config) {
+	t.Helper()
+
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(tlsServer.Close)
+	clientConfig := tlsServer.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	clientConfig.ServerName = "127.0.0.1"
+
+	s := &smtpServer{
+		tlsConfig: &tls.Config{Certificates: tlsServer.TLS.Certificates},
+		implicit:  implicit,
+		startTLS:  startTLS,
+		password:  "supersecret",
+	}
+
+	var err error
+	if implicit {
+		s.listener, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	} else {
+		s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.listener.Close() })
+
+	go s.serve()
+
+	return s, clientConfig
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	encrypted := s.implicit
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	readLine := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	reply("220 localhost ESMTP test")
+	for {
+		line := readLine()
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO" || cmd == "HELO":
+			reply("250-localhost")
+			if s.startTLS && !encrypted {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN LOGIN")
+		case cmd == "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+			encrypted = true
+		case strings.HasPrefix(strings.ToUpper(line), "AUTH PLAIN"):
+			raw, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(line[len("AUTH PLAIN"):]))
+			parts := strings.Split(string(raw), "\x00")
+			s.authenticate("PLAIN", parts[1], parts[2], reply)
+		case strings.HasPrefix(strings.ToUpper(line), "AUTH LOGIN"):
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+			username, _ := base64.StdEncoding.DecodeString(readLine())
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+			password, _ := base64.StdEncoding.DecodeString(readLine())
+			s.authenticate("LOGIN", string(username), string(password), reply)
+		case cmd == "MAIL":
+			s.mu.Lock()
+			s.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "RCPT":
+			s.mu.Lock()
+			s.receivers = append(s.receivers, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for l := readLine(); l != "."; l = readLine() {
+				data.WriteString(l + "\n")
+			}
+			s.mu.Lock()
+			s.data, s.encrypted, s.delivered = data.String(), encrypted, true
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		case line == "":
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) authenticate(mechanism, username, password string, reply func(string)) {
+	s.mu.Lock()
+	s.mechanism, s.username = mechanism, username
+	s.mu.Unlock()
+
+	if password != s.password {
+		reply("535 authentication failed")
+		return
+	}
+	reply("235 authenticated")
+}
+
+func TestEmail_Send(t *testing.T) {
+	tests := []struct {
+		name          string
+		implicit      bool
+		startTLS      bool
+		tlsMode       string
+		auth          string
+		password      string
+		wantMechanism string
+		wantEncrypted bool
+		wantErr       bool
+	}{
+		{
+			name:          "StartTLSPlain",
+			startTLS:      true,
+			tlsMode:       config.SMTPTLSStartTLS,
+			auth:          config.SMTPAuthPlain,
+			password:      "supersecret",
+			wantMechanism: "PLAIN",
+			wantEncrypted: true,
+		},
+		{
+			name:          "ImplicitTLSLogin",
+			implicit:      true,
+			tlsMode:       config.SMTPTLSImplicit,
+			auth:          config.SMTPAuthLogin,
+			password:      "supersecret",
+			wantMechanism: "LOGIN",
+			wantEncrypted: true,
+		},
+		{
+			name:     "PlainNoAuth",
+			tlsMode:  config.SMTPTLSNone,
+			auth:     config.SMTPAuthNone,
+			password: "",
+		},
+		{
+			name:     "StartTLSNotSupported",
+			tlsMode:  config.SMTPTLSStartTLS,
+			auth:     config.SMTPAuthNone,
+			password: "",
+			wantErr:  true,
+		},
+		{
+			name:          "WrongPassword",
+			startTLS:      true,
+			tlsMode:       config.SMTPTLSStartTLS,
+			auth:          config.SMTPAuthPlain,
+			password:      "wrong",
+			wantMechanism: "PLAIN",
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, tlsConfig := newSMTPServer(t, tt.implicit, tt.startTLS)
+
+			email := Email{
+				TLSConfig:         tlsConfig,
+				Auth:              tt.auth,
+				SenderAddress:     "updater@example.com",
+				SenderName:        "DNS Updater",
+				SenderPassword:    tt.password,
+				SMTPHost:          "127.0.0.1",
+				TLSMode:           tt.tlsMode,
+				ReceiverAddresses: []string{"john@example.com", "jane@example.com"},
+				SMTPPort:          server.port(),
+			}
+
+			err := email.Send(Message{Subject: "Public IP Address Changed", Text: "Your IP address has changed\n"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v; wantErr %t", err, tt.wantErr)
+			}
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+
+			if server.mechanism != tt.wantMechanism {
+				t.Errorf("auth mechanism = %q; want %q", server.mechanism, tt.wantMechanism)
+			}
+
+			if tt.wantErr {
+				if server.delivered {
+					t.Error("email delivered; want it to be aborted")
+				}
+				return
+			}
+
+			if server.encrypted != tt.wantEncrypted {
+				t.Errorf("encrypted = %t; want %t", server.encrypted, tt.wantEncrypted)
+			}
+			if tt.wantMechanism != "" && server.username != "updater@example.com" {
+				t.Errorf("username = %s; want updater@example.com", server.username)
+			}
+			if server.from != "updater@example.com" {
+				t.Errorf("from = %s; want updater@example.com", server.from)
+			}
+			if strings.Join(server.receivers, ",") != "john@example.com,jane@example.com" {
+				t.Errorf("receivers = %v; want [john@example.com jane@example.com]", server.receivers)
+			}
+			for _, header := range []string{
+				`From: "DNS Updater" <updater@example.com>`,
+				"To: john@example.com, jane@example.com",
+				"Subject: Public IP Address Changed",
+			} {
+				if !strings.Contains(server.data, header+"\n") {
+					t.Errorf("data = %q; want header %q", server.data, header)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginAuth_RefusesUnencrypted(t *testing.T) {
+	auth := &loginAuth{username: "user", password: "pass", host: "smtp.example.com"}
+
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com"}); err == nil {
+		t.Error("Start() error = nil; want an error on an unencrypted connection")
+	}
+
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true}); err != nil {
+		t.Errorf("Start() error = %v; want nil on a TLS connection", err)
+	}
+}
+
+func TestEmail_Message(t *testing.T) {
+	email := Email{SenderAddress: "updater@example.com", ReceiverAddresses: []string{"john@example.com"}}
+
+	data, err := email.message(Message{
+		Subject: "IP geändert",
+		Text:    "New IP: 1.2.3.4\n",
+		HTML:    "<p>New IP: <strong>1.2.3.4</strong></p>",
+	})
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "IP geändert" {
+		t.Errorf("Subject = %q (%v); want %q", subject, err, "IP geändert")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s (%v); want multipart/alternative", mediaType, err)
+	}
+
+	var types, bodies []string
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+
+	if strings.Join(types, ",") != "text/plain; charset=UTF-8,text/html; charset=UTF-8" {
+		t.Errorf("parts = %v; want the text and html alternatives", types)
+	}
+	if len(bodies) == 2 && (bodies[0] != "New IP: 1.2.3.4\r\n" || !strings.Contains(bodies[1], "<strong>1.2.3.4</strong>")) {
+		t.Errorf("bodies = %q; want the text and html versions", bodies)
+	}
+}
+
+func TestEmail_Message_TextOnly(t *testing.T) {
+	email := Email{SenderAddress: "updater@example.com", ReceiverAddresses: []string{"john@example.com"}}
+
+	data, err := email.message(Message{Subject: "IP changed", Text: "New IP: 1.2.3.4\n"})
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	if contentType := msg.Header.Get("Content-Type"); contentType != "text/plain; charset=UTF-8" {
+		t.Errorf("Content-Type = %s; want text/plain; charset=UTF-8", contentType)
+	}
+
+	body, _ := io.ReadAll(msg.Body)
+	if string(body) != "New IP: 1.2.3.4\r\n" {
+		t.Errorf("body = %q; want the text version", body)
+	}
+}
@@ -1,59 +1,174 @@
 package notifier
 
 import (
-	"net/smtp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
 	"go.uber.org/zap"
 )
 
-type Notifier struct {
-	Email Email
+// Notifier sends notifications through a channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
 }
 
-type Email struct {
-	SenderAddress   string
-	SenderPassword  string
-	ReceiverAddress string
-	SMTPServer      string
-	SMTPPort        string
+// EventKind is what an Event notifies
+type EventKind string
+
+const (
+	// EventUpdated is sent when the records were updated with a new ip address
+	EventUpdated EventKind = "updated"
+	// EventUpdateFailed is sent when the records could not be updated
+	EventUpdateFailed EventKind = "update_failed"
+	// EventDiscoveryFailed is sent when the ip address could not be discovered for a while
+	EventDiscoveryFailed EventKind = "discovery_failed"
+	// EventRecovered is sent when a failure notified before is over
+	EventRecovered EventKind = "recovered"
+)
+
+// Event describes a change of the public ip address and the records it was applied to,
+// or a failure preventing the records from being kept up to date
+type Event struct {
+	Since    *time.Time          `json:"since,omitempty"`
+	Time     time.Time           `json:"time"`
+	Failed   map[string][]string `json:"failed,omitempty"`
+	Updated  map[string][]string `json:"updated,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Family   string              `json:"family"`
+	Hostname string              `json:"hostname"`
+	IP       string              `json:"ip,omitempty"`
+	Kind     EventKind           `json:"kind"`
+	OldIP    string              `json:"old_ip,omitempty"`
+	DryRun   bool                `json:"dry_run"`
 }
 
-// New creates a new Notifier
-func New(cfg *config.Config) *Notifier {
-	zap.S().Debug("Creating notifier")
-	return &Notifier{
-		Email: Email{
-			SenderAddress:   cfg.SenderAddress,
-			SenderPassword:  cfg.SenderPassword,
-			ReceiverAddress: cfg.ReceiverAddress,
-			SMTPServer:      "smtp.gmail.com",
-			SMTPPort:        "587",
-		},
+// group identifies the events of the same kind and family, see Throttle. The recovery of
+// an update, which has the ip, is told apart from the recovery of the discovery.
+func (e Event) group() string {
+	switch {
+	case e.Kind == EventRecovered && e.IP != "":
+		return string(e.Kind) + "|update|" + e.Family
+	case e.Kind == EventRecovered:
+		return string(e.Kind) + "|discovery|" + e.Family
+	default:
+		return string(e.Kind) + "|" + e.Family
 	}
 }
 
-// SendEmail sends an email notification
-func (n *Notifier) SendEmail(updatedRecords map[string][]string, newIP string) error {
-	zap.S().Info("Sending email notification")
-	auth := smtp.PlainAuth("", n.Email.SenderAddress, n.Email.SenderPassword, n.Email.SMTPServer)
+// key identifies the events notifying the same thing within their group. Update events are
+// identified by the ip, since a retry doesn't know the previous one.
+func (e Event) key() string {
+	if e.IP != "" {
+		return e.group() + "|" + e.IP
+	}
 
-	to := []string{n.Email.ReceiverAddress}
-	msg := []byte("To: " + n.Email.ReceiverAddress + "\r\n" + "Subject: Public IP Address Changed\r\n" + "\r\n" + "Your IP address has changed to " + newIP + " for the following record(s):" + "\r\n")
+	return e.group()
+}
 
-	for zone, records := range updatedRecords {
-		msg = append(msg, []byte(zone+"\r\n")...)
-		for _, record := range records {
-			msg = append(msg, []byte("\t- "+record+"\r\n")...)
-		}
-	}
+// ChannelError is the error of a single notification channel
+type ChannelError struct {
+	Err     error
+	Channel string
+}
 
-	err := smtp.SendMail(n.Email.SMTPServer+":"+n.Email.SMTPPort, auth, n.Email.SenderAddress, to, msg)
+func (e *ChannelError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Channel, e.Err)
+}
+
+func (e *ChannelError) Unwrap() error {
+	return e.Err
+}
+
+// Dispatcher is a Notifier sending every notification to all the configured channels
+type Dispatcher struct {
+	Notifiers []Notifier
+}
+
+// New creates a Dispatcher with the channels enabled in the config
+func New(cfg *config.Config) (*Dispatcher, error) {
+	zap.S().Debug("Creating notifier")
+	templates, err := LoadTemplates(cfg.NotificationTemplateFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	zap.S().Info("Email notification sent")
+	client := &http.Client{Timeout: 30 * time.Second}
+	d := &Dispatcher{}
+
+	if cfg.EmailEnabled() {
+		d.Notifiers = append(d.Notifiers, &Email{
+			Templates:         templates,
+			Auth:              cfg.SMTPAuth,
+			ReceiverAddresses: cfg.ReceiverAddress,
+			SenderAddress:     cfg.SenderAddress,
+			SenderName:        cfg.SenderName,
+			SenderPassword:    cfg.SenderPassword,
+			SMTPHost:          cfg.SMTPHost,
+			SMTPPort:          cfg.SMTPPort,
+			TLSMode:           cfg.SMTPTLS,
+		})
+	}
+	if cfg.WebhookURL != "" {
+		d.Notifiers = append(d.Notifiers, &Webhook{HTTPClient: client, Templates: templates, URL: cfg.WebhookURL})
+	}
+	if cfg.SlackWebhookURL != "" {
+		d.Notifiers = append(d.Notifiers, &Slack{HTTPClient: client, Templates: templates, URL: cfg.SlackWebhookURL})
+	}
+	if cfg.DiscordWebhookURL != "" {
+		d.Notifiers = append(d.Notifiers, &Discord{HTTPClient: client, Templates: templates, URL: cfg.DiscordWebhookURL})
+	}
+	if cfg.TelegramBotToken != "" {
+		d.Notifiers = append(d.Notifiers, &Telegram{HTTPClient: client, Templates: templates, BotToken: cfg.TelegramBotToken, ChatID: cfg.TelegramChatID})
+	}
+	if cfg.NtfyURL != "" {
+		d.Notifiers = append(d.Notifiers, &Ntfy{HTTPClient: client, Templates: templates, Token: cfg.NtfyToken, URL: cfg.NtfyURL})
+	}
+	if cfg.GotifyURL != "" {
+		d.Notifiers = append(d.Notifiers, &Gotify{HTTPClient: client, Templates: templates, Token: cfg.GotifyToken, URL: cfg.GotifyURL})
+	}
+
+	for _, n := range d.Notifiers {
+		zap.S().Infof("Notification channel enabled: %s", n.Name())
+	}
+
+	return d, nil
+}
+
+// Name returns the name of the channel
+func (d *Dispatcher) Name() string {
+	return "dispatcher"
+}
+
+// Notify sends the event to all the channels at the same time. A failing channel doesn't prevent
+// the others from being notified, and its error is reported as a ChannelError.
+func (d *Dispatcher) Notify(ctx context.Context, event Event) error {
+	if len(d.Notifiers) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(d.Notifiers))
+	var wg sync.WaitGroup
+	for i, n := range d.Notifiers {
+		wg.Add(1)
+		go func(i int, n Notifier) {
+			defer wg.Done()
+			err := n.Notify(ctx, event)
+			metrics.Notifications.Inc(n.Name(), metrics.Result(err))
+			if err != nil {
+				errs[i] = &ChannelError{Err: err, Channel: n.Name()}
+				return
+			}
+			zap.S().Infof("%s notification sent", n.Name())
+		}(i, n)
+	}
+	wg.Wait()
 
-	return nil
+	return errors.Join(errs...)
 }
@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+)
+
+// recordingNotifier is a Notifier remembering the events it received
+type recordingNotifier struct {
+	err    error
+	name   string
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recordingNotifier) Name() string {
+	return r.name
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestNew(t *testing.T) {
+	cfg := &config.Config{
+		DiscordWebhookURL: "https://discord.com/api/webhooks/1/abc",
+		NtfyURL:           "https://ntfy.sh/home",
+		SenderAddress:     "updater@example.com",
+		SMTPHost:          "mail.example.com",
+		TelegramBotToken:  "123:abc",
+		TelegramChatID:    "42",
+		ReceiverAddress:   []string{"john@example.com"},
+		SMTPPort:          465,
+	}
+
+	d, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var names []string
+	for _, n := range d.Notifiers {
+		names = append(names, n.Name())
+	}
+	if strings.Join(names, ",") != "email,discord,telegram,ntfy" {
+		t.Errorf("Notifiers = %v; want [email discord telegram ntfy]", names)
+	}
+
+	email := d.Notifiers[0].(*Email)
+	if email.SMTPHost != "mail.example.com" || email.SMTPPort != 465 || len(email.ReceiverAddresses) != 1 {
+		t.Errorf("Email = %+v; want the server and receivers of the config", email)
+	}
+
+	if d, _ = New(&config.Config{}); len(d.Notifiers) != 0 {
+		t.Errorf("New() without channels has %d notifiers; want 0", len(d.Notifiers))
+	}
+
+	if _, err = New(&config.Config{NotificationTemplateFile: "/nonexistent/notification.tmpl"}); err == nil {
+		t.Error("New() error = nil; want an error for the missing template file")
+	}
+}
+
+func TestDispatcher_Notify(t *testing.T) {
+	ok := &recordingNotifier{name: "ok"}
+	failing := &recordingNotifier{name: "failing", err: errors.New("boom")}
+	d := &Dispatcher{Notifiers: []Notifier{failing, ok}}
+
+	err := d.Notify(context.Background(), Event{IP: "1.2.3.4"})
+
+	var channelErr *ChannelError
+	if !errors.As(err, &channelErr) || channelErr.Channel != "failing" {
+		t.Fatalf("Notify() error = %v; want a ChannelError of the failing channel", err)
+	}
+	if err.Error() != "failing: boom" {
+		t.Errorf("Notify() error = %q; want %q", err, "failing: boom")
+	}
+
+	if len(ok.events) != 1 || len(failing.events) != 1 {
+		t.Errorf("events = %d, %d; want every channel to be notified once", len(ok.events), len(failing.events))
+	}
+
+	if err := (&Dispatcher{}).Notify(context.Background(), Event{}); err != nil {
+		t.Errorf("Notify() without channels error = %v; want nil", err)
+	}
+}
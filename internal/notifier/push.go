@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Ntfy is a Notifier publishing to a ntfy topic, e.g. https://ntfy.sh/mytopic
+type Ntfy struct {
+	HTTPClient HTTPClient
+	Templates  *Templates
+	Token      string
+	URL        string
+}
+
+// Name returns the name of the channel
+func (n *Ntfy) Name() string {
+	return "ntfy"
+}
+
+// Notify publishes the event to the topic
+func (n *Ntfy) Notify(ctx context.Context, event Event) error {
+	msg, err := n.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Title", msg.Subject)
+	header.Set("Tags", "globe_with_meridians")
+	if n.Token != "" {
+		header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	return post(ctx, n.HTTPClient, n.URL, strings.NewReader(msg.Text), header)
+}
+
+// Gotify is a Notifier pushing messages to a Gotify server
+type Gotify struct {
+	HTTPClient HTTPClient
+	Templates  *Templates
+	Token      string
+	URL        string
+}
+
+// Name returns the name of the channel
+func (g *Gotify) Name() string {
+	return "gotify"
+}
+
+// Notify pushes the event as a message of the application owning the token
+func (g *Gotify) Notify(ctx context.Context, event Event) error {
+	msg, err := g.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("X-Gotify-Key", g.Token)
+
+	payload := map[string]interface{}{
+		"title":    msg.Subject,
+		"message":  msg.Text,
+		"priority": 5,
+	}
+
+	return postJSON(ctx, g.HTTPClient, strings.TrimSuffix(g.URL, "/")+"/message", payload, header)
+}
@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"strings"
+	texttemplate "text/template"
+)
+
+var (
+	//go:embed templates/default.txt.tmpl
+	defaultTextTemplates string
+	//go:embed templates/default.html.tmpl
+	defaultHTMLTemplates string
+)
+
+// Templates renders the notifications. The "subject" and "text" templates are plain text,
+// the "html" template is used for the HTML part of the emails.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Message is a rendered notification
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// LoadTemplates parses the default templates, overridden by the ones defined in the file.
+// The file can redefine any of "subject", "text" and "html" with {{define}} blocks;
+// the ones it doesn't define keep their default.
+func LoadTemplates(path string) (*Templates, error) {
+	t := newDefaultTemplates()
+
+	if path == "" {
+		return t, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the notification templates: %w", err)
+	}
+
+	// both kinds are parsed from the same file, each one only executes its own definitions
+	if _, err = t.text.New("file").Parse(string(content)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err = t.html.New("file").Parse(string(content)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+// newDefaultTemplates parses the embedded templates
+func newDefaultTemplates() *Templates {
+	return &Templates{
+		text: texttemplate.Must(texttemplate.New("default").Parse(defaultTextTemplates)),
+		html: htmltemplate.Must(htmltemplate.New("default").Parse(defaultHTMLTemplates)),
+	}
+}
+
+// Render renders the event. Nil Templates use the defaults.
+func (t *Templates) Render(event Event) (msg Message, err error) {
+	if t == nil {
+		t = newDefaultTemplates()
+	}
+
+	var buf bytes.Buffer
+	if err = t.text.ExecuteTemplate(&buf, "subject", event); err != nil {
+		return msg, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err = t.text.ExecuteTemplate(&buf, "text", event); err != nil {
+		return msg, err
+	}
+	msg.Text = buf.String()
+
+	buf.Reset()
+	if err = t.html.ExecuteTemplate(&buf, "html", event); err != nil {
+		return msg, err
+	}
+	msg.HTML = buf.String()
+
+	return msg, nil
+}
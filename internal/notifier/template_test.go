@@ -0,0 +1,179 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	return Event{
+		Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Failed:   map[string][]string{"example.org": {"<b>broken</b>.example.org"}},
+		Updated:  map[string][]string{"example.org": {"example.org"}, "example.com": {"home.example.com", "vpn.example.com"}},
+		Family:   "IPv4",
+		Hostname: "homelab",
+		IP:       "203.0.113.2",
+		OldIP:    "203.0.113.1",
+	}
+}
+
+func TestTemplates_Default(t *testing.T) {
+	msg, err := (*Templates)(nil).Render(testEvent())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if msg.Subject != "Public IP Address Changed" {
+		t.Errorf("Subject = %q; want %q", msg.Subject, "Public IP Address Changed")
+	}
+
+	wantText := "Your IPv4 address has changed to 203.0.113.2 (was 203.0.113.1) for the following record(s):\n" +
+		"example.com\n\t- home.example.com\n\t- vpn.example.com\n" +
+		"example.org\n\t- example.org\n\n" +
+		"The following record(s) could not be updated:\n" +
+		"example.org\n\t- <b>broken</b>.example.org\n\n" +
+		"Sent by homelab at 2026-01-02 03:04:05 UTC\n"
+	if msg.Text != wantText {
+		t.Errorf("Text = %q; want %q", msg.Text, wantText)
+	}
+
+	for _, want := range []string{"<strong>203.0.113.2</strong>", "<li>vpn.example.com</li>", "&lt;b&gt;broken&lt;/b&gt;.example.org"} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("HTML = %s; want it to contain %s", msg.HTML, want)
+		}
+	}
+
+	event := testEvent()
+	event.DryRun = true
+	msg, err = (*Templates)(nil).Render(event)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.HasPrefix(msg.Subject, "[DRY RUN]") || !strings.Contains(msg.Text, "dry run") {
+		t.Errorf("Subject = %q, Text = %q; want a dry run notification", msg.Subject, msg.Text)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantSubject string
+		wantText    string
+		wantHTML    string
+		wantErr     bool
+	}{
+		{
+			name:        "OverrideSubject",
+			content:     `{{define "subject"}}[{{.Hostname}}] {{.IP}}{{end}}`,
+			wantSubject: "[homelab] 203.0.113.2",
+			wantText:    "Your IPv4 address has changed",
+			wantHTML:    "<strong>203.0.113.2</strong>",
+		},
+		{
+			name: "OverrideAll",
+			content: `{{define "subject"}}DNS updated{{end}}
+{{define "text"}}{{.OldIP}} -> {{.IP}}{{end}}
+{{define "html"}}<p>{{range .Failed}}{{range .}}{{.}}{{end}}{{end}}</p>{{end}}`,
+			wantSubject: "DNS updated",
+			wantText:    "203.0.113.1 -> 203.0.113.2",
+			wantHTML:    "<p>&lt;b&gt;broken&lt;/b&gt;.example.org</p>",
+		},
+		{
+			name:    "Invalid",
+			content: `{{define "subject"}}{{.IP}{{end}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "notification.tmpl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			templates, err := LoadTemplates(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTemplates() error = %v; wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			msg, err := templates.Render(testEvent())
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q; want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.Text, tt.wantText) {
+				t.Errorf("Text = %q; want it to contain %q", msg.Text, tt.wantText)
+			}
+			if !strings.Contains(msg.HTML, tt.wantHTML) {
+				t.Errorf("HTML = %q; want it to contain %q", msg.HTML, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestTemplates_Kinds(t *testing.T) {
+	since := time.Date(2026, 1, 2, 1, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		event       Event
+		wantSubject string
+		wantText    string
+	}{
+		{
+			name:        "UpdateFailed",
+			event:       Event{Kind: EventUpdateFailed, Failed: map[string][]string{"example.com": {"home.example.com"}}, Error: "permission denied", Family: "IPv4", IP: "203.0.113.2"},
+			wantSubject: "DNS Record Update Failed",
+			wantText:    "could not be updated:\nexample.com\n\t- home.example.com\n\npermission denied\n",
+		},
+		{
+			name:        "DiscoveryFailed",
+			event:       Event{Kind: EventDiscoveryFailed, Since: &since, Error: "ipify: timeout", Family: "IPv6"},
+			wantSubject: "Public IPv6 Discovery Failing",
+			wantText:    "can't be discovered since 2026-01-02 01:00:00 UTC, the records won't be updated until it is:\nipify: timeout\n",
+		},
+		{
+			name:        "DiscoveryRecovered",
+			event:       Event{Kind: EventRecovered, Since: &since, Family: "IPv4"},
+			wantSubject: "DNS Auto Updater Recovered",
+			wantText:    "can be discovered again, after failing since 2026-01-02 01:00:00 UTC.\n",
+		},
+		{
+			name:        "UpdateRecovered",
+			event:       Event{Kind: EventRecovered, Updated: map[string][]string{"example.com": {"home.example.com"}}, Family: "IPv4", IP: "203.0.113.2"},
+			wantSubject: "DNS Auto Updater Recovered",
+			wantText:    "failed before were updated to 203.0.113.2:\nexample.com\n\t- home.example.com\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.Hostname = "homelab"
+			msg, err := (*Templates)(nil).Render(tt.event)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q; want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.Text, tt.wantText) {
+				t.Errorf("Text = %q; want it to contain %q", msg.Text, tt.wantText)
+			}
+			if !strings.Contains(msg.HTML, "homelab") {
+				t.Errorf("HTML = %q; want it to be rendered", msg.HTML)
+			}
+		})
+	}
+}
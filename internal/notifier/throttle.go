@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Throttle is a Notifier dropping the events identical to the last one of their kind and family
+// sent within Interval, so a flapping ip provider or a record failing at every retry doesn't flood
+// the channels, while a change back to a previous ip is still notified
+type Throttle struct {
+	Notifier Notifier
+	Now      func() time.Time
+	sent     map[string]sentEvent
+	Interval time.Duration
+	mu       sync.Mutex
+}
+
+// sentEvent is the last event sent of a group
+type sentEvent struct {
+	at  time.Time
+	key string
+}
+
+// NewThrottle creates a new Throttle sending the events to the given Notifier
+func NewThrottle(notifier Notifier, interval time.Duration) *Throttle {
+	return &Throttle{
+		Notifier: notifier,
+		Now:      time.Now,
+		sent:     make(map[string]sentEvent),
+		Interval: interval,
+	}
+}
+
+// Name returns the name of the throttled Notifier
+func (t *Throttle) Name() string {
+	return t.Notifier.Name()
+}
+
+// Notify sends the event, unless it's the same as the last one of its group, sent within Interval.
+// An event that failed to be sent isn't remembered, so it's sent again next time.
+func (t *Throttle) Notify(ctx context.Context, event Event) error {
+	group, key := event.group(), event.key()
+	now := t.Now()
+
+	t.mu.Lock()
+	last, ok := t.sent[group]
+	if ok && last.key == key && now.Sub(last.at) < t.Interval {
+		t.mu.Unlock()
+		zap.S().Debugf("Skipping %s notification, already sent at %s", event.Kind, last.at.Format(time.RFC3339))
+		return nil
+	}
+	for g, sent := range t.sent {
+		if now.Sub(sent.at) >= t.Interval {
+			delete(t.sent, g)
+		}
+	}
+	current := sentEvent{at: now, key: key}
+	t.sent[group] = current
+	t.mu.Unlock()
+
+	err := t.Notifier.Notify(ctx, event)
+	if err != nil {
+		t.mu.Lock()
+		if t.sent[group] == current {
+			// the previous event is the last one the channels received
+			if ok {
+				t.sent[group] = last
+			} else {
+				delete(t.sent, group)
+			}
+		}
+		t.mu.Unlock()
+	}
+
+	return err
+}
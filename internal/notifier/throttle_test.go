@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottle_Notify(t *testing.T) {
+	recorder := &recordingNotifier{name: "recording"}
+	throttle := NewThrottle(recorder, time.Hour)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	throttle.Now = func() time.Time { return now }
+
+	outage := Event{Kind: EventDiscoveryFailed, Family: "IPv4", Error: "timeout"}
+	steps := []struct {
+		name     string
+		event    Event
+		advance  time.Duration
+		fail     bool
+		wantSent bool
+	}{
+		{name: "First", event: outage, wantSent: true},
+		{name: "Duplicate", event: outage, advance: 10 * time.Minute, wantSent: false},
+		{name: "DifferentError", event: Event{Kind: EventDiscoveryFailed, Family: "IPv4", Error: "refused"}, wantSent: false},
+		{name: "OtherFamily", event: Event{Kind: EventDiscoveryFailed, Family: "IPv6"}, wantSent: true},
+		{name: "Recovered", event: Event{Kind: EventRecovered, Family: "IPv4"}, wantSent: true},
+		{name: "AfterInterval", event: outage, advance: time.Hour, wantSent: true},
+		{name: "NewIP", event: Event{Kind: EventUpdated, Family: "IPv4", IP: "203.0.113.2"}, wantSent: true},
+		{name: "OtherIP", event: Event{Kind: EventUpdated, Family: "IPv4", IP: "203.0.113.3"}, wantSent: true},
+		{name: "SendFails", event: Event{Kind: EventUpdateFailed, Family: "IPv4", IP: "203.0.113.3"}, fail: true, wantSent: true},
+		{name: "RetriedAfterFailure", event: Event{Kind: EventUpdateFailed, Family: "IPv4", IP: "203.0.113.3", OldIP: "203.0.113.2"}, wantSent: true},
+		{name: "SameFailureOnRetry", event: Event{Kind: EventUpdateFailed, Family: "IPv4", IP: "203.0.113.3"}, wantSent: false},
+		{name: "DiscoveryRecovered", event: Event{Kind: EventRecovered, Family: "IPv4"}, wantSent: true},
+		{name: "UpdateRecovered", event: Event{Kind: EventRecovered, Family: "IPv4", IP: "203.0.113.3"}, wantSent: true},
+		{name: "ChangeToB", event: Event{Kind: EventUpdated, Family: "IPv4", IP: "203.0.113.5"}, wantSent: true},
+		{name: "ChangeBackToA", event: Event{Kind: EventUpdated, Family: "IPv4", IP: "203.0.113.3"}, wantSent: true},
+		{name: "RepeatedA", event: Event{Kind: EventUpdated, Family: "IPv4", IP: "203.0.113.3"}, wantSent: false},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			now = now.Add(step.advance)
+			recorder.err = nil
+			if step.fail {
+				recorder.err = errors.New("boom")
+			}
+			before := len(recorder.events)
+
+			err := throttle.Notify(context.Background(), step.event)
+			if (err != nil) != step.fail {
+				t.Fatalf("Notify() error = %v; want failure %t", err, step.fail)
+			}
+
+			if sent := len(recorder.events) > before; sent != step.wantSent {
+				t.Errorf("Notify() sent = %t; want %t", sent, step.wantSent)
+			}
+		})
+	}
+}
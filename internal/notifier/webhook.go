@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Webhook is a Notifier posting the event as JSON to an URL
+type Webhook struct {
+	HTTPClient HTTPClient
+	Templates  *Templates
+	URL        string
+}
+
+// Name returns the name of the channel
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify posts the event, with its subject and text, to the webhook
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	msg, err := w.Templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	payload := struct {
+		Event
+		Subject string `json:"subject"`
+		Text    string `json:"text"`
+	}{event, msg.Subject, msg.Text}
+
+	return postJSON(ctx, w.HTTPClient, w.URL, payload, nil)
+}
+
+// postJSON posts the payload encoded as JSON, and fails on a non 2xx response
+func postJSON(ctx context.Context, client HTTPClient, url string, payload interface{}, header http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json")
+
+	return post(ctx, client, url, bytes.NewReader(body), header)
+}
+
+// post sends the body to the URL, and fails on a non 2xx response. The URL is redacted
+// from the errors, since the path of the webhooks and the Telegram bot API holds a secret.
+func post(ctx context.Context, client HTTPClient, reqURL string, body io.Reader, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, body)
+	if err != nil {
+		return redactURL(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return redactURL(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("unexpected HTTP status code %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// redactURL replaces the URL of a request error with its scheme and host only
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	redacted := "<redacted>"
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil && u.Host != "" {
+		redacted = u.Scheme + "://" + u.Host + "/<redacted>"
+	}
+	urlErr.URL = redacted
+
+	return err
+}
@@ -0,0 +1,168 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+func TestNotifiers(t *testing.T) {
+	event := Event{
+		Updated: map[string][]string{"example.com": {"home.example.com"}},
+		Family:  "IPv4",
+		IP:      "1.2.3.4",
+	}
+
+	msg, err := (*Templates)(nil).Render(event)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		notifier   func(client HTTPClient) Notifier
+		wantURL    string
+		wantHeader map[string]string
+		wantBody   map[string]interface{}
+		wantText   string
+	}{
+		{
+			name: "Webhook",
+			notifier: func(client HTTPClient) Notifier {
+				return &Webhook{HTTPClient: client, URL: "https://hooks.example.com/dns"}
+			},
+			wantURL:    "https://hooks.example.com/dns",
+			wantHeader: map[string]string{"Content-Type": "application/json"},
+			wantBody:   map[string]interface{}{"ip": "1.2.3.4", "family": "IPv4", "subject": "Public IP Address Changed"},
+		},
+		{
+			name: "Slack",
+			notifier: func(client HTTPClient) Notifier {
+				return &Slack{HTTPClient: client, URL: "https://hooks.slack.com/services/T/B/X"}
+			},
+			wantURL:  "https://hooks.slack.com/services/T/B/X",
+			wantBody: map[string]interface{}{"text": "*Public IP Address Changed*\n" + msg.Text},
+		},
+		{
+			name: "Discord",
+			notifier: func(client HTTPClient) Notifier {
+				return &Discord{HTTPClient: client, URL: "https://discord.com/api/webhooks/1/abc"}
+			},
+			wantURL:  "https://discord.com/api/webhooks/1/abc",
+			wantBody: map[string]interface{}{"content": "**Public IP Address Changed**\n" + msg.Text},
+		},
+		{
+			name: "Telegram",
+			notifier: func(client HTTPClient) Notifier {
+				return &Telegram{HTTPClient: client, BotToken: "123:abc", ChatID: "42"}
+			},
+			wantURL:  "https://api.telegram.org/bot123:abc/sendMessage",
+			wantBody: map[string]interface{}{"chat_id": "42", "text": "Public IP Address Changed\n\n" + msg.Text},
+		},
+		{
+			name: "Ntfy",
+			notifier: func(client HTTPClient) Notifier {
+				return &Ntfy{HTTPClient: client, Token: "tk_secret", URL: "https://ntfy.sh/home"}
+			},
+			wantURL:    "https://ntfy.sh/home",
+			wantHeader: map[string]string{"Title": "Public IP Address Changed", "Authorization": "Bearer tk_secret"},
+			wantText:   msg.Text,
+		},
+		{
+			name: "Gotify",
+			notifier: func(client HTTPClient) Notifier {
+				return &Gotify{HTTPClient: client, Token: "AppToken", URL: "https://gotify.example.com/"}
+			},
+			wantURL:    "https://gotify.example.com/message",
+			wantHeader: map[string]string{"X-Gotify-Key": "AppToken"},
+			wantBody:   map[string]interface{}{"title": "Public IP Address Changed", "message": msg.Text},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			var body []byte
+			client := &mocks.MockClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					req = r
+					body, _ = io.ReadAll(r.Body)
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+				},
+			}
+
+			if err := tt.notifier(client).Notify(context.Background(), event); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+
+			if req.Method != http.MethodPost || req.URL.String() != tt.wantURL {
+				t.Errorf("request = %s %s; want POST %s", req.Method, req.URL, tt.wantURL)
+			}
+
+			for key, value := range tt.wantHeader {
+				if got := req.Header.Get(key); got != value {
+					t.Errorf("header %s = %q; want %q", key, got, value)
+				}
+			}
+
+			if tt.wantText != "" && string(body) != tt.wantText {
+				t.Errorf("body = %q; want %q", body, tt.wantText)
+			}
+
+			if tt.wantBody != nil {
+				var got map[string]interface{}
+				if err := json.Unmarshal(body, &got); err != nil {
+					t.Fatalf("body %q is not JSON: %v", body, err)
+				}
+				for key, value := range tt.wantBody {
+					if got[key] != value {
+						t.Errorf("body[%s] = %v; want %v", key, got[key], value)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNotifiers_HTTPError(t *testing.T) {
+	client := &mocks.MockClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("no_service\n"))}, nil
+		},
+	}
+
+	err := (&Slack{HTTPClient: client, URL: "https://hooks.slack.com/services/T/B/X"}).Notify(context.Background(), Event{})
+	if err == nil || !strings.Contains(err.Error(), "404: no_service") {
+		t.Errorf("Notify() error = %v; want the status code and the response", err)
+	}
+}
+
+func TestNotifiers_RedactsURL(t *testing.T) {
+	// a closed port, so the request fails with an error holding the URL
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "http://" + listener.Addr().String()
+	listener.Close()
+
+	client := &http.Client{}
+	notifiers := []Notifier{
+		&Telegram{HTTPClient: client, APIURL: addr, BotToken: "123456:SECRET", ChatID: "42"},
+		&Discord{HTTPClient: client, URL: addr + "/api/webhooks/1/SECRET"},
+		&Slack{HTTPClient: client, URL: addr + "/services/T/B/SECRET"},
+	}
+
+	for _, n := range notifiers {
+		err := n.Notify(context.Background(), Event{Kind: EventUpdated})
+		if err == nil || strings.Contains(err.Error(), "SECRET") {
+			t.Errorf("%s Notify() error = %v; want the request error without the secret", n.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Clock abstracts the passing of time, so the scheduler can be driven by tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Task is the job run by the scheduler. A returned error makes the scheduler back off.
+type Task func(ctx context.Context) error
+
+// Scheduler runs a task at a fixed interval with jitter, backing off exponentially on errors
+type Scheduler struct {
+	Clock      Clock
+	Rand       func() float64
+	trigger    chan struct{}
+	Interval   time.Duration
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Jitter is the maximum fraction of the delay randomly added to it
+	Jitter float64
+}
+
+// New creates a new Scheduler with the given interval and jitter fraction
+func New(interval time.Duration, jitter float64) *Scheduler {
+	minBackoff := 30 * time.Second
+	if minBackoff > interval {
+		minBackoff = interval
+	}
+
+	return &Scheduler{
+		Clock:      realClock{},
+		Rand:       rand.Float64,
+		trigger:    make(chan struct{}, 1),
+		Interval:   interval,
+		MinBackoff: minBackoff,
+		MaxBackoff: interval,
+		Jitter:     jitter,
+	}
+}
+
+// Trigger asks the scheduler to run the task immediately.
+// Multiple triggers received while the task is running are coalesced.
+func (s *Scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs the task immediately and then according to the schedule, until the context is done
+func (s *Scheduler) Run(ctx context.Context, task Task) {
+	failures := 0
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := task(ctx); err != nil {
+			failures++
+			zap.S().Warnf("Scheduled task failed %d time(s) in a row: %v", failures, err)
+		} else {
+			failures = 0
+		}
+
+		delay := s.NextDelay(failures)
+		zap.S().Debugf("Next run at %s", s.Clock.Now().Add(delay).Format(time.RFC3339))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.Clock.After(delay):
+		case <-s.trigger:
+			zap.S().Info("Run triggered manually")
+		}
+	}
+}
+
+// NextDelay returns how long to wait before the next run, given the number of consecutive failures
+func (s *Scheduler) NextDelay(failures int) time.Duration {
+	delay := s.Interval
+
+	if failures > 0 {
+		delay = s.MinBackoff
+		for i := 1; i < failures && delay < s.MaxBackoff; i++ {
+			delay *= 2
+		}
+		if delay > s.MaxBackoff {
+			delay = s.MaxBackoff
+		}
+	}
+
+	if s.Jitter > 0 && s.Rand != nil {
+		delay += time.Duration(float64(delay) * s.Jitter * s.Rand())
+	}
+
+	return delay
+}
@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+// fakeClock hands every requested delay to the test, which decides when it elapses
+type fakeClock struct {
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		waits: make(chan time.Duration),
+		fire:  make(chan time.Time),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, 0)
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.fire
+}
+
+func TestScheduler_NextDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		jitter   float64
+		rand     float64
+		expected time.Duration
+	}{
+		{
+			name:     "NoFailures",
+			failures: 0,
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "FirstFailure",
+			failures: 1,
+			expected: 30 * time.Second,
+		},
+		{
+			name:     "ThirdFailure",
+			failures: 3,
+			expected: 2 * time.Minute,
+		},
+		{
+			name:     "CappedAtInterval",
+			failures: 10,
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "WithJitter",
+			failures: 0,
+			jitter:   0.1,
+			rand:     0.5,
+			expected: 10*time.Minute + 30*time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(10*time.Minute, tt.jitter)
+			s.Rand = func() float64 { return tt.rand }
+
+			if delay := s.NextDelay(tt.failures); delay != tt.expected {
+				t.Errorf("NextDelay() = %s; want %s", delay, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	clock := newFakeClock()
+	s := New(10*time.Minute, 0)
+	s.Clock = clock
+
+	results := []error{errors.New("provider down"), errors.New("provider down"), nil}
+	calls := make(chan int, len(results)+2)
+	runs := 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx, func(ctx context.Context) error {
+			runs++
+			calls <- runs
+			if runs <= len(results) {
+				return results[runs-1]
+			}
+			return nil
+		})
+	}()
+
+	expectedWaits := []time.Duration{30 * time.Second, time.Minute, 10 * time.Minute}
+	for i, expected := range expectedWaits {
+		if run := <-calls; run != i+1 {
+			t.Fatalf("run = %d; want %d", run, i+1)
+		}
+		if wait := <-clock.waits; wait != expected {
+			t.Errorf("wait after run %d = %s; want %s", i+1, wait, expected)
+		}
+		if i < len(expectedWaits)-1 {
+			clock.fire <- time.Unix(0, 0)
+		}
+	}
+
+	// a manual trigger runs the task without waiting for the interval
+	s.Trigger()
+	if run := <-calls; run != 4 {
+		t.Fatalf("run after trigger = %d; want 4", run)
+	}
+	<-clock.waits
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// UnixPrefix marks an address as the path of a Unix socket
+const UnixPrefix = "unix:"
+
+// Server is an optional HTTP listener, exposing the metrics or the control API
+type Server struct {
+	HTTP *http.Server
+	Mux  *http.ServeMux
+}
+
+// New creates a new Server listening on addr, serving the metrics on /metrics
+func New(addr string) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	srv := NewWithHandler(addr, mux)
+	srv.Mux = mux
+
+	return srv
+}
+
+// NewWithHandler creates a new Server listening on addr, serving every request with the handler
+func NewWithHandler(addr string, handler http.Handler) *Server {
+	return &Server{
+		HTTP: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Run listens on the address of the server and serves the requests until the context is done
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := Listen(s.HTTP.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Listen listens on a TCP address, or on a Unix socket when the address starts with unix:.
+// A stale socket left by a previous run is removed, and the new one is only accessible by the owner.
+func Listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, UnixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// Serve serves the requests of the listener until the context is done, then shuts down gracefully
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	zap.S().Infof("HTTP server listening on %s", listener.Addr())
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.HTTP.Serve(listener)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.HTTP.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
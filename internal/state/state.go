@@ -0,0 +1,158 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// State is what the updater persists between restarts
+type State struct {
+	LastErrorAt time.Time `json:"last_error_at,omitempty"`
+	LastSync    time.Time `json:"last_sync,omitempty"`
+	// IPs is the last ip applied to every record, by family
+	IPs map[string]string `json:"ips"`
+	// Records is the last update of every record, by record id
+	Records   map[string]RecordState `json:"records"`
+	LastError string                 `json:"last_error,omitempty"`
+	// Events are the last changes of the managed records, oldest first
+	Events []RecordEvent `json:"events,omitempty"`
+}
+
+// MaxEvents is how many record events are kept
+const MaxEvents = 100
+
+// Record event actions
+const (
+	// EventAdded means the record started being managed
+	EventAdded = "added"
+	// EventRemoved means the record stopped being managed, because it was deleted or no longer matches
+	EventRemoved = "removed"
+	// EventCreated means the record was missing and was created by the updater
+	EventCreated = "created"
+	// EventDeleted means the record created by the updater was deleted because it's no longer configured
+	EventDeleted = "deleted"
+)
+
+// RecordEvent is a record that started or stopped being managed
+type RecordEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Type   string    `json:"type"`
+	Zone   string    `json:"zone"`
+}
+
+// RecordState is the last update applied to a record
+type RecordState struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Content   string    `json:"content"`
+	Name      string    `json:"name"`
+	Zone      string    `json:"zone"`
+}
+
+// AddEvent appends the event to the log, dropping the oldest ones beyond MaxEvents
+func (s *State) AddEvent(event RecordEvent) {
+	s.Events = append(s.Events, event)
+	if len(s.Events) > MaxEvents {
+		s.Events = append([]RecordEvent(nil), s.Events[len(s.Events)-MaxEvents:]...)
+	}
+}
+
+// Store loads and saves the state to a JSON file.
+// A store without path keeps the state in memory only.
+type Store struct {
+	Path string
+}
+
+// New creates a new Store
+func New(path string) *Store {
+	return &Store{Path: path}
+}
+
+// newState creates an empty State
+func newState() *State {
+	return &State{
+		IPs:     make(map[string]string),
+		Records: make(map[string]RecordState),
+	}
+}
+
+// Load reads the state file. A missing file results in an empty state.
+func (s *Store) Load() (state *State, err error) {
+	state = newState()
+	if s.Path == "" {
+		return state, nil
+	}
+
+	zap.S().Infof("Loading state from %s", s.Path)
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		zap.S().Info("No state file found, starting fresh")
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return newState(), err
+	}
+
+	if state.IPs == nil {
+		state.IPs = make(map[string]string)
+	}
+	if state.Records == nil {
+		state.Records = make(map[string]RecordState)
+	}
+
+	return state, nil
+}
+
+// Save atomically writes the state file, so a crash never leaves it half written
+func (s *Store) Save(state *State) (err error) {
+	if s.Path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), "."+filepath.Base(s.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+func TestStore_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	store := New(filepath.Join(dir, "state.json"))
+
+	updatedAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	state := &State{
+		IPs: map[string]string{"IPv4": "203.0.113.1"},
+		Records: map[string]RecordState{
+			"testRecordID": {UpdatedAt: updatedAt, Content: "203.0.113.1", Name: "home.example.com", Zone: "example.com"},
+		},
+		LastError: "testError",
+		LastSync:  updatedAt,
+	}
+
+	if err := store.Save(state); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if loaded.IPs["IPv4"] != "203.0.113.1" {
+		t.Errorf("Load() IPv4 = %s; want 203.0.113.1", loaded.IPs["IPv4"])
+	}
+
+	if record := loaded.Records["testRecordID"]; record != state.Records["testRecordID"] {
+		t.Errorf("Load() record = %+v; want %+v", record, state.Records["testRecordID"])
+	}
+
+	if loaded.LastError != "testError" || !loaded.LastSync.Equal(updatedAt) {
+		t.Errorf("Load() = %+v; want %+v", loaded, state)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Save() left %d files in the directory; want 1", len(entries))
+	}
+}
+
+func TestStore_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		path    string
+		wantErr bool
+	}{
+		{
+			name:    "MissingFile",
+			content: nil,
+			path:    "state.json",
+			wantErr: false,
+		},
+		{
+			name:    "NoPath",
+			content: nil,
+			path:    "",
+			wantErr: false,
+		},
+		{
+			name:    "CorruptedFile",
+			content: func() *string { s := "{not json"; return &s }(),
+			path:    "state.json",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.path != "" {
+				path = filepath.Join(t.TempDir(), tt.path)
+			}
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			state, err := New(path).Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if state == nil || state.IPs == nil || state.Records == nil {
+				t.Fatalf("Load() = %+v; want an empty state", state)
+			}
+		})
+	}
+}
+
+func TestState_AddEvent(t *testing.T) {
+	state := newState()
+	for i := 0; i < MaxEvents+5; i++ {
+		state.AddEvent(RecordEvent{Action: EventAdded, ID: fmt.Sprint(i)})
+	}
+
+	if len(state.Events) != MaxEvents {
+		t.Fatalf("AddEvent() kept %d events; want %d", len(state.Events), MaxEvents)
+	}
+
+	if first, last := state.Events[0].ID, state.Events[MaxEvents-1].ID; first != "5" || last != fmt.Sprint(MaxEvents+4) {
+		t.Errorf("AddEvent() kept events %s to %s; want the last ones, 5 to %d", first, last, MaxEvents+4)
+	}
+}
@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/state"
+)
+
+// Status is a snapshot of what the updater manages and of the outcome of the last updates.
+// It's safe to read while a sync is running.
+type Status struct {
+	LastSync  time.Time                  `json:"last_sync,omitempty"`
+	IPs       map[string]string          `json:"ips"`
+	Records   map[string][]dnsapi.Record `json:"records"`
+	Results   map[string]*Result         `json:"results"`
+	Zones     []dnsapi.Zone              `json:"zones"`
+	Events    []state.RecordEvent        `json:"events"`
+	LastError string                     `json:"last_error,omitempty"`
+}
+
+// Result is the outcome of the last update of the records of a family
+type Result struct {
+	Time      time.Time                 `json:"time"`
+	Created   map[string][]string       `json:"created,omitempty"`
+	Deleted   map[string][]string       `json:"deleted,omitempty"`
+	Failed    map[string][]FailedRecord `json:"failed,omitempty"`
+	Unchanged map[string][]string       `json:"unchanged,omitempty"`
+	Updated   map[string][]string       `json:"updated,omitempty"`
+	IP        string                    `json:"ip"`
+	DryRun    bool                      `json:"dry_run"`
+}
+
+// FailedRecord is a record that could not be updated
+type FailedRecord struct {
+	Error string `json:"error"`
+	Name  string `json:"name"`
+}
+
+// newResult summarizes the result of an update
+func newResult(ip string, now time.Time, result *dnsapi.UpdateResult) *Result {
+	failed := make(map[string][]FailedRecord, len(result.Failed))
+	for zoneName, failures := range result.Failed {
+		for _, failure := range failures {
+			failed[zoneName] = append(failed[zoneName], FailedRecord{Error: failure.Err.Error(), Name: failure.Record.Name})
+		}
+	}
+
+	return &Result{
+		Time:      now,
+		Created:   dnsapi.RecordNames(result.Created),
+		Deleted:   dnsapi.RecordNames(result.Deleted),
+		Failed:    failed,
+		Unchanged: dnsapi.RecordNames(result.Unchanged),
+		Updated:   dnsapi.RecordNames(result.Updated),
+		IP:        ip,
+		DryRun:    result.DryRun,
+	}
+}
+
+// Status returns the snapshot of the last sync
+func (u *Updater) Status() Status {
+	u.statusMu.Lock()
+	defer u.statusMu.Unlock()
+
+	return u.status
+}
+
+// publishStatus takes a new snapshot, called by the sync goroutine once the records are no longer modified
+func (u *Updater) publishStatus() {
+	status := Status{
+		LastSync:  u.State.LastSync,
+		IPs:       make(map[string]string, len(u.lastIPs)),
+		Records:   make(map[string][]dnsapi.Record, len(u.DNS.Records)),
+		Results:   make(map[string]*Result, len(u.results)),
+		Zones:     append([]dnsapi.Zone(nil), u.DNS.Zones...),
+		Events:    append([]state.RecordEvent(nil), u.State.Events...),
+		LastError: u.State.LastError,
+	}
+
+	for family, ip := range u.lastIPs {
+		status.IPs[family.String()] = ip
+	}
+	for zoneName, records := range u.DNS.Records {
+		status.Records[zoneName] = append([]dnsapi.Record(nil), records...)
+	}
+	for family, result := range u.results {
+		status.Results[family.String()] = result
+	}
+
+	u.statusMu.Lock()
+	u.status = status
+	u.statusMu.Unlock()
+}
+
+// Force makes the next sync update the records even if the ip didn't change,
+// e.g. after a record was modified by hand
+func (u *Updater) Force() {
+	u.force.Store(true)
+}
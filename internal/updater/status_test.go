@@ -0,0 +1,86 @@
+package updater
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/ipsource"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/state"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+)
+
+func TestUpdater_StatusAndForce(t *testing.T) {
+	requests := 0
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/zones") {
+				body := `{"success":true,"errors":[],"messages":[],"result":[{"id":"testZoneID","name":"example.com"}],"result_info":{"page":1,"per_page":50,"total_pages":1}}`
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}
+			if req.Method == http.MethodGet {
+				// the record was changed by hand
+				body := `{"success":true,"errors":[],"messages":[],"result":[{"id":"testRecordID1","name":"home.example.com","type":"A","content":"198.51.100.1"}],"result_info":{"page":1,"per_page":100,"total_pages":1}}`
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}
+
+			requests++
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &dnsapi.CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken", IPv4Enabled: true, ZoneIDs: []string{"testZoneID"}},
+		HTTPClient: mockClient,
+		Records: map[string][]dnsapi.Record{
+			"example.com": {{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"}},
+		},
+		Zones: []dnsapi.Zone{{ID: "testZoneID", Name: "example.com"}},
+	}
+
+	resolver, err := ipsource.NewResolver([]ipsource.Provider{&staticProvider{ip: "203.0.113.2"}}, 1)
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	u := New(dns, []*ipsource.Resolver{resolver}, nil, state.New(filepath.Join(t.TempDir(), "state.json")))
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	u.now = func() time.Time { return now }
+
+	if status := u.Status(); len(status.Zones) != 1 || len(status.Results) != 0 {
+		t.Errorf("Status() before a sync = %+v; want the zones and no results", status)
+	}
+
+	if err := u.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	status := u.Status()
+	if status.IPs["IPv4"] != "203.0.113.2" || status.Records["example.com"][0].Content != "203.0.113.2" {
+		t.Errorf("Status() = %+v; want the new ip and records", status)
+	}
+	result := status.Results["IPv4"]
+	if result == nil || strings.Join(result.Updated["example.com"], ",") != "home.example.com" {
+		t.Errorf("Status() results = %+v; want home.example.com updated", status.Results)
+	}
+	if !status.LastSync.Equal(now) || !u.State.Records["testRecordID1"].UpdatedAt.Equal(now) {
+		t.Errorf("Status() last sync = %s, record updated at %s; want the updater clock %s", status.LastSync, u.State.Records["testRecordID1"].UpdatedAt, now)
+	}
+
+	// the ip didn't change, only a forced sync reloads the records and fixes them
+	if err := u.Sync(context.Background()); err != nil || requests != 1 {
+		t.Fatalf("Sync() = %v with %d requests; want nil with 1 request", err, requests)
+	}
+
+	u.Force()
+	if err := u.Sync(context.Background()); err != nil || requests != 2 {
+		t.Fatalf("forced Sync() = %v with %d requests; want nil with 2 requests", err, requests)
+	}
+}
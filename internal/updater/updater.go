@@ -0,0 +1,351 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/ipsource"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/metrics"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/notifier"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/state"
+	"go.uber.org/zap"
+)
+
+// Updater keeps the DNS records in sync with the current public ip addresses
+type Updater struct {
+	DNS       *dnsapi.CFDNS
+	Notifier  notifier.Notifier
+	State     *state.State
+	Store     *state.Store
+	now       func() time.Time
+	lastIPs   map[ipsource.Family]string
+	outages   map[ipsource.Family]*outage
+	pending   map[ipsource.Family]*dnsapi.UpdateResult
+	results   map[ipsource.Family]*Result
+	Resolvers []*ipsource.Resolver
+	// OutageThreshold is how long the ip discovery of a family must fail before
+	// a notification is sent. 0 disables the discovery notifications.
+	OutageThreshold time.Duration
+	status          Status
+	force           atomic.Bool
+	statusMu        sync.Mutex
+	// syncMu serializes the syncs and the refreshes, which both modify the records
+	syncMu sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// outage tracks a family whose ip can't be discovered
+type outage struct {
+	since    time.Time
+	notified bool
+}
+
+// New creates a new Updater, restoring the last applied ip addresses from the store
+func New(dns *dnsapi.CFDNS, resolvers []*ipsource.Resolver, notify notifier.Notifier, store *state.Store) *Updater {
+	u := &Updater{
+		DNS:       dns,
+		Notifier:  notify,
+		Store:     store,
+		now:       time.Now,
+		lastIPs:   make(map[ipsource.Family]string),
+		outages:   make(map[ipsource.Family]*outage),
+		pending:   make(map[ipsource.Family]*dnsapi.UpdateResult),
+		results:   make(map[ipsource.Family]*Result),
+		Resolvers: resolvers,
+	}
+
+	st, err := store.Load()
+	if err != nil {
+		zap.S().Warnf("Error loading state, starting fresh: %v", err)
+	}
+	u.State = st
+
+	for _, resolver := range resolvers {
+		if ip, ok := st.IPs[resolver.Family.String()]; ok {
+			zap.S().Infof("Last applied %s: %s", resolver.Family, ip)
+			u.lastIPs[resolver.Family] = ip
+		}
+	}
+	u.publishStatus()
+
+	return u
+}
+
+// Sync resolves the current ip of every family and updates the records when it changed.
+// Families are handled independently, so a failure of one doesn't prevent the other from
+// being updated. Records that failed in the previous run are retried even if the ip didn't change.
+func (u *Updater) Sync(ctx context.Context) error {
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+
+	var errs []error
+
+	if u.force.Swap(false) {
+		zap.S().Info("Forcing the update of the records")
+		if err := u.refresh(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error refreshing the records: %w", err))
+		}
+		u.lastIPs = make(map[ipsource.Family]string)
+	}
+	u.deleteUnconfigured(ctx)
+
+	for _, resolver := range u.Resolvers {
+		ip, err := resolver.Resolve(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			if ctx.Err() == nil {
+				u.discoveryFailed(resolver.Family, err)
+			}
+			continue
+		}
+		u.discoveryRecovered(resolver.Family)
+
+		if err := u.syncFamily(ctx, resolver.Family, ip); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err := errors.Join(errs...)
+	u.saveState(err)
+	u.publishStatus()
+
+	return err
+}
+
+// Refresh discovers the zones and records again, logging the records that started or stopped being managed
+func (u *Updater) Refresh(ctx context.Context) error {
+	u.syncMu.Lock()
+	defer u.syncMu.Unlock()
+
+	err := u.refresh(ctx)
+	if err != nil {
+		zap.S().Errorf("Error refreshing the records, keeping the current ones: %v", err)
+	}
+
+	if err := u.Store.Save(u.State); err != nil {
+		zap.S().Errorf("Error saving state: %v", err)
+	}
+	u.publishStatus()
+
+	return err
+}
+
+// refresh discovers the zones and records again and records the changes
+func (u *Updater) refresh(ctx context.Context) error {
+	changes, err := u.DNS.Refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	u.recordChanges(state.EventAdded, changes.Added)
+	u.recordChanges(state.EventRemoved, changes.Removed)
+	u.deleteUnconfigured(ctx)
+
+	return nil
+}
+
+// deleteUnconfigured deletes the records created by the updater that are no longer configured,
+// and records their deletion in the event log
+func (u *Updater) deleteUnconfigured(ctx context.Context) {
+	u.recordChanges(state.EventDeleted, u.DNS.DeleteUnconfigured(ctx))
+}
+
+// recordChanges logs the records that started or stopped being managed in the event log
+func (u *Updater) recordChanges(action string, records map[string][]dnsapi.Record) {
+	for zoneName, zoneRecords := range records {
+		for _, record := range zoneRecords {
+			zap.S().Infof("%s record %s in zone %s %s", record.Type, record.Name, zoneName, action)
+			if action == state.EventRemoved || action == state.EventDeleted {
+				delete(u.State.Records, record.ID)
+			}
+
+			u.State.AddEvent(state.RecordEvent{
+				Time:   u.now(),
+				Action: action,
+				ID:     record.ID,
+				Name:   record.Name,
+				Type:   record.Type,
+				Zone:   zoneName,
+			})
+		}
+	}
+}
+
+// Wait waits for the notifications still being sent
+func (u *Updater) Wait() {
+	u.wg.Wait()
+}
+
+// syncFamily updates the records of a family if its ip changed, or retries the ones that previously failed
+func (u *Updater) syncFamily(ctx context.Context, family ipsource.Family, ip string) (err error) {
+	var result *dnsapi.UpdateResult
+
+	switch {
+	case ip != u.lastIPs[family]:
+		zap.S().Infof("New %s detected: %s", family, ip)
+		result, err = u.DNS.UpdateRecords(ctx, ip)
+	case u.pending[family] != nil:
+		zap.S().Infof("Retrying the %s records that failed to update", family)
+		result, err = u.DNS.RetryFailed(ctx, ip, u.pending[family])
+	case u.DNS.HasMissing(dnsapi.RecordTypeForIP(ip)) && !u.DNS.Cfg.DryRun:
+		zap.S().Infof("Creating the missing %s records", family)
+		result, err = u.DNS.CreateMissing(ctx, ip)
+	default:
+		zap.S().Debugf("%s unchanged: %s", family, ip)
+		return nil
+	}
+
+	oldIP := u.lastIPs[family]
+	if oldIP == ip {
+		// a retry, the previous address is already the current one
+		oldIP = ""
+	}
+	// a new ip clearing the failures is notified as an update, so the change isn't hidden
+	recovered := u.pending[family] != nil && len(result.Failed) == 0 && u.lastIPs[family] == ip
+	u.recordChanges(state.EventRemoved, result.Deleted)
+	if !result.DryRun {
+		u.recordChanges(state.EventCreated, result.Created)
+	}
+	u.lastIPs[family] = ip
+	u.results[family] = newResult(ip, u.now(), result)
+	if len(result.Failed) > 0 {
+		u.pending[family] = result
+	} else {
+		delete(u.pending, family)
+	}
+
+	// nothing was applied in a dry run, so there is nothing to remember
+	if !result.DryRun {
+		u.recordState(family, ip, result)
+		if len(result.Updated) > 0 {
+			metrics.IPChanged.SetToTime(u.now(), family.String())
+		}
+	}
+
+	event := notifier.Event{
+		Failed:  dnsapi.RecordNames(result.FailedRecords()),
+		Updated: dnsapi.RecordNames(result.Updated),
+		Family:  family.String(),
+		IP:      ip,
+		OldIP:   oldIP,
+		DryRun:  result.DryRun,
+	}
+
+	switch {
+	case recovered:
+		event.Kind = notifier.EventRecovered
+		u.notify(event)
+	case len(result.Updated) > 0:
+		event.Kind = notifier.EventUpdated
+		u.notify(event)
+	case len(result.Failed) > 0:
+		event.Kind = notifier.EventUpdateFailed
+		event.Error = err.Error()
+		u.notify(event)
+	}
+
+	return err
+}
+
+// discoveryFailed tracks the outage of the ip discovery of a family, and notifies it
+// once it has lasted longer than the threshold
+func (u *Updater) discoveryFailed(family ipsource.Family, err error) {
+	o := u.outages[family]
+	if o == nil {
+		o = &outage{since: u.now()}
+		u.outages[family] = o
+	}
+
+	if u.OutageThreshold <= 0 || o.notified || u.now().Sub(o.since) < u.OutageThreshold {
+		return
+	}
+
+	o.notified = true
+	u.notify(notifier.Event{
+		Kind:   notifier.EventDiscoveryFailed,
+		Since:  &o.since,
+		Error:  err.Error(),
+		Family: family.String(),
+	})
+}
+
+// discoveryRecovered ends the outage of the ip discovery of a family, notifying the recovery
+// if the outage was notified
+func (u *Updater) discoveryRecovered(family ipsource.Family) {
+	o := u.outages[family]
+	if o == nil {
+		return
+	}
+
+	delete(u.outages, family)
+	if o.notified {
+		zap.S().Infof("%s discovery recovered after %s", family, u.now().Sub(o.since).Round(time.Second))
+		u.notify(notifier.Event{
+			Kind:   notifier.EventRecovered,
+			Since:  &o.since,
+			Family: family.String(),
+		})
+	}
+}
+
+// notify sends the event in the background, so a slow channel doesn't delay the sync
+func (u *Updater) notify(event notifier.Event) {
+	if u.Notifier == nil {
+		return
+	}
+
+	event.Time = u.now()
+	event.Hostname, _ = os.Hostname()
+
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := u.Notifier.Notify(ctx, event); err != nil {
+			zap.S().Errorf("Error sending %s notification: %v", event.Kind, err)
+		}
+	}()
+}
+
+// recordState stores the ip and the records applied by an update
+func (u *Updater) recordState(family ipsource.Family, ip string, result *dnsapi.UpdateResult) {
+	if len(result.Failed) == 0 {
+		u.State.IPs[family.String()] = ip
+	}
+
+	now := u.now()
+	for zoneName, records := range result.Updated {
+		for _, record := range records {
+			u.State.Records[record.ID] = state.RecordState{
+				UpdatedAt: now,
+				Content:   record.Content,
+				Name:      record.Name,
+				Zone:      zoneName,
+			}
+		}
+	}
+}
+
+// saveState records the outcome of a sync and persists the state
+func (u *Updater) saveState(syncErr error) {
+	now := u.now()
+	u.State.LastSync = now
+	if syncErr != nil {
+		u.State.LastError = syncErr.Error()
+		u.State.LastErrorAt = now
+	} else {
+		u.State.LastError = ""
+		metrics.LastSuccessfulSync.SetToTime(now)
+	}
+
+	if err := u.Store.Save(u.State); err != nil {
+		zap.S().Errorf("Error saving state: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/cmd/dnsapi"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/config"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/ipsource"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/notifier"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/internal/state"
+	"github.com/daruzero/cloudflare-dns-auto-updater-go/test/mocks"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
+
+type staticProvider struct {
+	err error
+	ip  string
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+func (p *staticProvider) GetIP(_ context.Context) (string, error) {
+	return p.ip, p.err
+}
+
+func TestUpdater_Sync(t *testing.T) {
+	var requestedIDs []string
+	failing := map[string]bool{"testRecordID2": true}
+
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			recordID := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			requestedIDs = append(requestedIDs, recordID)
+
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"` + recordID + `","name":"` + recordID + `.example.com","type":"A","content":"203.0.113.2"}}`
+			statusCode := 200
+			if failing[recordID] {
+				body = `{"success":false,"errors":[{"code":10001,"message":"Internal error"}],"messages":[],"result":null}`
+				statusCode = 500
+			}
+			return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &dnsapi.CFDNS{
+		Cfg:        &config.Config{AuthKey: "testAuthKey", Email: "testEmail"},
+		HTTPClient: mockClient,
+		Records: map[string][]dnsapi.Record{
+			"example.com": {
+				{ID: "testRecordID1", Name: "testRecordID1.example.com", Type: "A", Content: "203.0.113.1"},
+				{ID: "testRecordID2", Name: "testRecordID2.example.com", Type: "A", Content: "203.0.113.1"},
+			},
+		},
+	}
+
+	provider := &staticProvider{ip: "203.0.113.2"}
+	resolver, err := ipsource.NewResolver([]ipsource.Provider{provider}, 1)
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	store := state.New(filepath.Join(t.TempDir(), "state.json"))
+	u := New(dns, []*ipsource.Resolver{resolver}, nil, store)
+
+	steps := []struct {
+		name        string
+		expectedIDs string
+		wantErr     bool
+		fixFailing  bool
+	}{
+		{name: "NewIP", expectedIDs: "testRecordID1,testRecordID2", wantErr: true},
+		{name: "RetryFailedOnly", expectedIDs: "testRecordID2", wantErr: true},
+		{name: "RetrySucceeds", expectedIDs: "testRecordID2", wantErr: false, fixFailing: true},
+		{name: "NothingToDo", expectedIDs: "", wantErr: false},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			requestedIDs = nil
+			if step.fixFailing {
+				failing = map[string]bool{}
+			}
+
+			err := u.Sync(context.Background())
+			if (err != nil) != step.wantErr {
+				t.Fatalf("Sync() error = %v, wantErr %v", err, step.wantErr)
+			}
+
+			if ids := strings.Join(requestedIDs, ","); ids != step.expectedIDs {
+				t.Errorf("Sync() updated %s; want %s", ids, step.expectedIDs)
+			}
+		})
+	}
+
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if saved.IPs["IPv4"] != "203.0.113.2" {
+		t.Errorf("saved IPv4 = %s; want 203.0.113.2", saved.IPs["IPv4"])
+	}
+
+	if len(saved.Records) != 2 || saved.LastError != "" {
+		t.Errorf("saved state = %+v; want 2 records and no error", saved)
+	}
+
+	// a restarted updater doesn't update the records again
+	requestedIDs = nil
+	restarted := New(dns, []*ipsource.Resolver{resolver}, nil, store)
+	if err := restarted.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() after restart error = %v", err)
+	}
+
+	if len(requestedIDs) != 0 {
+		t.Errorf("Sync() after restart updated %v; want nothing", requestedIDs)
+	}
+}
+
+// recordingNotifier is a notifier.Notifier remembering the events it received
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notifier.Event
+}
+
+func (r *recordingNotifier) Name() string {
+	return "recording"
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event notifier.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestUpdater_Notify(t *testing.T) {
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &dnsapi.CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken"},
+		HTTPClient: mockClient,
+		Records: map[string][]dnsapi.Record{
+			"example.com": {{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"}},
+		},
+	}
+
+	resolver, err := ipsource.NewResolver([]ipsource.Provider{&staticProvider{ip: "203.0.113.2"}}, 1)
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	notify := &recordingNotifier{}
+	u := New(dns, []*ipsource.Resolver{resolver}, notify, state.New(""))
+
+	for i := 0; i < 2; i++ {
+		if err := u.Sync(context.Background()); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	}
+	u.Wait()
+
+	if len(notify.events) != 1 {
+		t.Fatalf("notified %d times; want only for the ip change", len(notify.events))
+	}
+
+	event := notify.events[0]
+	if event.Kind != notifier.EventUpdated || event.IP != "203.0.113.2" || event.Family != "IPv4" || event.DryRun {
+		t.Errorf("event = %+v; want the new IPv4 address", event)
+	}
+	if records := event.Updated["example.com"]; len(records) != 1 || records[0] != "home.example.com" {
+		t.Errorf("event.Updated = %v; want home.example.com", event.Updated)
+	}
+}
+
+func TestUpdater_NotifyFailures(t *testing.T) {
+	failing := true
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if failing {
+				body := `{"success":false,"errors":[{"code":10001,"message":"Internal error"}],"messages":[],"result":null}`
+				return &http.Response{StatusCode: 500, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.2"}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &dnsapi.CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken"},
+		HTTPClient: mockClient,
+		Records: map[string][]dnsapi.Record{
+			"example.com": {{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"}},
+		},
+	}
+
+	provider := &staticProvider{ip: "203.0.113.2"}
+	resolver, err := ipsource.NewResolver([]ipsource.Provider{provider}, 1)
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	notify := &recordingNotifier{}
+	u := New(dns, []*ipsource.Resolver{resolver}, notify, state.New(""))
+	u.OutageThreshold = time.Hour
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	u.now = func() time.Time { return now }
+
+	steps := []struct {
+		name        string
+		advance     time.Duration
+		providerErr error
+		ip          string
+		breakDNS    bool
+		fixDNS      bool
+		wantKind    notifier.EventKind
+	}{
+		{name: "UpdateFails", wantKind: notifier.EventUpdateFailed},
+		{name: "RetryFails", wantKind: notifier.EventUpdateFailed},
+		{name: "RetrySucceeds", fixDNS: true, wantKind: notifier.EventRecovered},
+		{name: "DiscoveryFails", providerErr: errors.New("timeout")},
+		{name: "DiscoveryStillFailing", advance: 30 * time.Minute, providerErr: errors.New("timeout")},
+		{name: "DiscoveryOutage", advance: 30 * time.Minute, providerErr: errors.New("timeout"), wantKind: notifier.EventDiscoveryFailed},
+		{name: "OutageNotifiedOnce", advance: 30 * time.Minute, providerErr: errors.New("timeout")},
+		{name: "DiscoveryRecovers", advance: 30 * time.Minute, wantKind: notifier.EventRecovered},
+		{name: "NothingToNotify", advance: 30 * time.Minute},
+		{name: "NewIPFails", ip: "203.0.113.3", breakDNS: true, wantKind: notifier.EventUpdateFailed},
+		{name: "OtherIPClearsFailure", ip: "203.0.113.4", fixDNS: true, wantKind: notifier.EventUpdated},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			now = now.Add(step.advance)
+			provider.err = step.providerErr
+			if step.ip != "" {
+				provider.ip = step.ip
+			}
+			if step.breakDNS {
+				failing = true
+			}
+			if step.fixDNS {
+				failing = false
+			}
+			before := len(notify.events)
+
+			_ = u.Sync(context.Background())
+			u.Wait()
+
+			var kinds []notifier.EventKind
+			for _, event := range notify.events[before:] {
+				kinds = append(kinds, event.Kind)
+			}
+
+			if step.wantKind == "" && len(kinds) > 0 {
+				t.Errorf("notified %v; want nothing", kinds)
+			}
+			if step.wantKind != "" && (len(kinds) != 1 || kinds[0] != step.wantKind) {
+				t.Errorf("notified %v; want [%s]", kinds, step.wantKind)
+			}
+		})
+	}
+
+	if changed := notify.events[len(notify.events)-1]; changed.OldIP != "203.0.113.3" || changed.IP != "203.0.113.4" {
+		t.Errorf("update event = %+v; want the change from 203.0.113.3 to 203.0.113.4", changed)
+	}
+
+	outage := notify.events[3]
+	if outage.Since == nil || !outage.Since.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) || outage.Error == "" {
+		t.Errorf("outage event = %+v; want the start of the outage and its error", outage)
+	}
+}
+
+func TestUpdater_Refresh(t *testing.T) {
+	records := `{"id":"testRecordID1","name":"home.example.com","type":"A","content":"203.0.113.1"}`
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			result := `{"id":"testZoneID","name":"example.com"}`
+			if strings.HasSuffix(req.URL.Path, "/dns_records") {
+				result = records
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":[` + result + `],"result_info":{"page":1,"per_page":100,"total_pages":1}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &dnsapi.CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken", IPv4Enabled: true, ZoneIDs: []string{"testZoneID"}},
+		HTTPClient: mockClient,
+		Records: map[string][]dnsapi.Record{
+			"example.com": {{ID: "testRecordID2", Name: "nas.example.com", Type: "A", Content: "203.0.113.1"}},
+		},
+		Zones: []dnsapi.Zone{{ID: "testZoneID", Name: "example.com"}},
+	}
+
+	store := state.New(filepath.Join(t.TempDir(), "state.json"))
+	u := New(dns, nil, nil, store)
+	u.State.Records["testRecordID2"] = state.RecordState{Name: "nas.example.com"}
+
+	if err := u.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	var events []string
+	for _, event := range u.Status().Events {
+		events = append(events, event.Action+" "+event.Name)
+	}
+	if strings.Join(events, ",") != "added home.example.com,removed nas.example.com" {
+		t.Errorf("Status() events = %v; want home.example.com added and nas.example.com removed", events)
+	}
+
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := saved.Records["testRecordID2"]; ok || len(saved.Events) != 2 {
+		t.Errorf("saved state = %+v; want the removed record forgotten and the events logged", saved)
+	}
+}
+
+func TestUpdater_DeleteUnconfigured(t *testing.T) {
+	var deleted []string
+	mockClient := &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodDelete {
+				deleted = append(deleted, req.URL.Path)
+			}
+			body := `{"success":true,"errors":[],"messages":[],"result":{"id":"testRecordID2"}}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	dns := &dnsapi.CFDNS{
+		Cfg:        &config.Config{APIToken: "testToken", IPv4Enabled: true, CreateMissingRecords: true, DeleteRemovedRecords: true},
+		HTTPClient: mockClient,
+		Records: map[string][]dnsapi.Record{
+			"example.com": {{ID: "testRecordID1", Name: "home.example.com", Type: "A", Content: "203.0.113.1"}},
+		},
+		Unconfigured: map[string][]dnsapi.Record{
+			"example.com": {{ID: "testRecordID2", Name: "old.example.com", Type: "A", ZoneID: "testZoneID", Comment: dnsapi.ManagedComment}},
+		},
+	}
+
+	resolver, err := ipsource.NewResolver([]ipsource.Provider{&staticProvider{ip: "203.0.113.1"}}, 1)
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	u := New(dns, []*ipsource.Resolver{resolver}, nil, state.New(""))
+	u.State.Records["testRecordID2"] = state.RecordState{Name: "old.example.com"}
+
+	if err := u.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if len(deleted) != 1 || !strings.HasSuffix(deleted[0], "/testRecordID2") {
+		t.Errorf("Sync() deleted %v; want old.example.com", deleted)
+	}
+
+	events := u.Status().Events
+	if len(events) != 1 || events[0].Action != state.EventDeleted || events[0].Name != "old.example.com" {
+		t.Errorf("Status() events = %+v; want old.example.com deleted", events)
+	}
+	if _, ok := u.State.Records["testRecordID2"]; ok {
+		t.Error("Sync() kept the state of the deleted record; want it forgotten")
+	}
+}
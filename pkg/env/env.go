@@ -1,62 +1,158 @@
 package env
 
 import (
-	"go.uber.org/zap"
-	"log"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-// GetEnv returns the value of the environment variable
-func GetEnv(name string, required bool, fallback string) string {
+var (
+	// ErrRequired is returned when a required environment variable is not set
+	ErrRequired = errors.New("is required")
+	// ErrInvalid is returned when an environment variable can't be parsed
+	ErrInvalid = errors.New("is invalid")
+	// ErrConflict is returned when both an environment variable and its _FILE form are set
+	ErrConflict = errors.New("are both set")
+)
+
+// FileSuffix is appended to the name of an environment variable to read its value from a file,
+// e.g. Docker or Kubernetes secrets
+const FileSuffix = "_FILE"
+
+// lookup returns the value of the environment variable, or an error if it's required and not set.
+// When NAME_FILE is set instead of NAME, the value is read from the referenced file.
+func lookup(name string, required bool) (value string, err error) {
 	zap.S().Debugf("Loading environment variable %s", name)
-	value := os.Getenv(name)
+	value = os.Getenv(name)
+
+	if path := os.Getenv(name + FileSuffix); path != "" {
+		if value != "" {
+			return "", fmt.Errorf("environment variables %s and %s%s %w, only one can be used", name, name, FileSuffix, ErrConflict)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("environment variable %s%s: %w", name, FileSuffix, err)
+		}
+		value = strings.TrimRight(string(content), "\r\n")
+	}
 
 	if required && value == "" {
-		zap.S().Fatalf("Environment variable %s is required", name)
+		return "", fmt.Errorf("environment variable %s %w", name, ErrRequired)
+	}
+
+	return value, nil
+}
+
+// invalid returns the error for an environment variable that can't be parsed
+func invalid(name, expected string) error {
+	return fmt.Errorf("environment variable %s %w: must be %s", name, ErrInvalid, expected)
+}
+
+// GetEnv returns the value of the environment variable
+func GetEnv(name string, required bool, fallback string) (string, error) {
+	value, err := lookup(name, required)
+	if err != nil {
+		return fallback, err
 	}
 
 	if value == "" {
-		value = fallback
+		return fallback, nil
 	}
 
-	return value
+	return value, nil
 }
 
 // GetEnvAsInt returns the value of the environment variable as an integer
-func GetEnvAsInt(name string, required bool, fallback int) int {
-	zap.S().Debugf("Loading environment variable %s", name)
-	value := os.Getenv(name)
+func GetEnvAsInt(name string, required bool, fallback int) (int, error) {
+	value, err := lookup(name, required)
+	if err != nil || value == "" {
+		return fallback, err
+	}
 
-	if required && value == "" {
-		log.Fatalf("Environment variable %s is required", name)
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fallback, invalid(name, "an integer")
 	}
 
-	if value == "" {
-		return fallback
+	return i, nil
+}
+
+// GetEnvAsBool returns the value of the environment variable as a boolean
+func GetEnvAsBool(name string, required bool, fallback bool) (bool, error) {
+	value, err := lookup(name, required)
+	if err != nil || value == "" {
+		return fallback, err
 	}
 
-	i, err := strconv.Atoi(value)
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
 	if err != nil {
-		log.Fatalf("Environment variable %s must be an integer", name)
+		return fallback, invalid(name, "a boolean")
 	}
 
-	return i
+	return b, nil
 }
 
-// GetEnvAsStringSlice returns the value of the environment variable as a string slice
-func GetEnvAsStringSlice(name string, required bool, fallback []string) []string {
-	zap.S().Debugf("Loading environment variable %s", name)
-	value := os.Getenv(name)
+// GetEnvAsDuration returns the value of the environment variable as a duration.
+// Plain integers are read as seconds, anything else as a Go duration (e.g. 1h30m).
+func GetEnvAsDuration(name string, required bool, fallback time.Duration) (time.Duration, error) {
+	value, err := lookup(name, required)
+	if err != nil || value == "" {
+		return fallback, err
+	}
 
-	if required && value == "" {
-		log.Fatalf("Environment variable %s is required", name)
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
 	}
 
-	if value == "" {
-		return fallback
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback, invalid(name, "a number of seconds or a duration like 1h30m")
+	}
+
+	return d, nil
+}
+
+// GetEnvAsURL returns the value of the environment variable as an absolute URL
+func GetEnvAsURL(name string, required bool, fallback *url.URL) (*url.URL, error) {
+	value, err := lookup(name, required)
+	if err != nil || value == "" {
+		return fallback, err
+	}
+
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fallback, invalid(name, "an absolute URL")
+	}
+
+	return u, nil
+}
+
+// GetEnvAsStringSlice returns the value of the environment variable as a comma separated string slice.
+// Whitespace around the items is trimmed and empty items are dropped.
+func GetEnvAsStringSlice(name string, required bool, fallback []string) ([]string, error) {
+	value, err := lookup(name, required)
+	if err != nil || value == "" {
+		return fallback, err
+	}
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	if required && len(items) == 0 {
+		return fallback, fmt.Errorf("environment variable %s %w", name, ErrRequired)
 	}
 
-	return strings.Split(value, ",")
+	return items, nil
 }
@@ -1,9 +1,15 @@
 package env
 
 import (
+	"errors"
+	"io/fs"
+	"net/url"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -26,6 +32,21 @@ func teardown() {
 	}
 }
 
+// checkErr fails the test when err doesn't match the wanted sentinel error
+func checkErr(t *testing.T, err, wantErr error) {
+	t.Helper()
+
+	if wantErr == nil && err != nil {
+		t.Fatalf("Function() error = %v; want nil", err)
+	}
+	if wantErr != nil && !errors.Is(err, wantErr) {
+		t.Fatalf("Function() error = %v; want %v", err, wantErr)
+	}
+	if err != nil && !strings.Contains(err.Error(), testKey) {
+		t.Errorf("Function() error = %v; want it to name %s", err, testKey)
+	}
+}
+
 func TestGetEnv(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -34,7 +55,7 @@ func TestGetEnv(t *testing.T) {
 		required bool
 		fallback string
 		expected string
-		wantExit bool
+		wantErr  error
 	}{
 		{
 			name:     "Required",
@@ -43,7 +64,6 @@ func TestGetEnv(t *testing.T) {
 			required: true,
 			fallback: "",
 			expected: testString,
-			wantExit: false,
 		},
 		{
 			name:     "RequiredMissing",
@@ -52,7 +72,7 @@ func TestGetEnv(t *testing.T) {
 			required: true,
 			fallback: "",
 			expected: "",
-			wantExit: true,
+			wantErr:  ErrRequired,
 		},
 		{
 			name:     "NotRequired",
@@ -61,7 +81,6 @@ func TestGetEnv(t *testing.T) {
 			required: false,
 			fallback: "",
 			expected: "",
-			wantExit: false,
 		},
 		{
 			name:     "NotRequiredWithFallback",
@@ -70,29 +89,14 @@ func TestGetEnv(t *testing.T) {
 			required: false,
 			fallback: testString,
 			expected: testString,
-			wantExit: false,
 		},
-		// Add more test cases here
 	}
 
 	for _, tt := range tests {
 		setup(tt.value)
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.wantExit {
-				if os.Getenv("BE_CRASHER") == "1" {
-					GetEnv(tt.key, tt.required, tt.fallback)
-					return
-				}
-				cmd := exec.Command(os.Args[0], "-test.run=TestGetEnv")
-				cmd.Env = append(os.Environ(), "BE_CRASHER=1")
-				err := cmd.Run()
-				if e, ok := err.(*exec.ExitError); ok && !e.Success() {
-					return
-				}
-				t.Fatalf("process ran with err %v, want exit status 1", err)
-			}
-
-			value := GetEnv(tt.key, tt.required, tt.fallback)
+			value, err := GetEnv(tt.key, tt.required, tt.fallback)
+			checkErr(t, err, tt.wantErr)
 
 			if value != tt.expected {
 				t.Errorf("Function() = %s; want %s", value, tt.expected)
@@ -110,7 +114,7 @@ func TestGetEnvAsInt(t *testing.T) {
 		required bool
 		fallback int
 		expected int
-		wantExit bool
+		wantErr  error
 	}{
 		{
 			name:     "Required",
@@ -119,7 +123,6 @@ func TestGetEnvAsInt(t *testing.T) {
 			required: true,
 			fallback: 0,
 			expected: 1,
-			wantExit: false,
 		},
 		{
 			name:     "RequiredMissing",
@@ -127,17 +130,16 @@ func TestGetEnvAsInt(t *testing.T) {
 			value:    "",
 			required: true,
 			fallback: 0,
-			expected: 1,
-			wantExit: true,
+			expected: 0,
+			wantErr:  ErrRequired,
 		},
 		{
 			name:     "NotRequired",
 			key:      testKey,
-			value:    "1",
+			value:    " 1 ",
 			required: false,
 			fallback: 0,
 			expected: 1,
-			wantExit: false,
 		},
 		{
 			name:     "NotRequiredWithFallback",
@@ -146,29 +148,23 @@ func TestGetEnvAsInt(t *testing.T) {
 			required: false,
 			fallback: 1,
 			expected: 1,
-			wantExit: false,
 		},
-		// Add more test cases here
+		{
+			name:     "Invalid",
+			key:      testKey,
+			value:    "one",
+			required: false,
+			fallback: 1,
+			expected: 1,
+			wantErr:  ErrInvalid,
+		},
 	}
 
 	for _, tt := range tests {
 		setup(tt.value)
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.wantExit {
-				if os.Getenv("BE_CRASHER") == "1" {
-					GetEnvAsInt(tt.key, tt.required, tt.fallback)
-					return
-				}
-				cmd := exec.Command(os.Args[0], "-test.run=TestGetEnv")
-				cmd.Env = append(os.Environ(), "BE_CRASHER=1")
-				err := cmd.Run()
-				if e, ok := err.(*exec.ExitError); ok && !e.Success() {
-					return
-				}
-				t.Fatalf("process ran with err %v, want exit status 1", err)
-			}
-
-			value := GetEnvAsInt(tt.key, tt.required, tt.fallback)
+			value, err := GetEnvAsInt(tt.key, tt.required, tt.fallback)
+			checkErr(t, err, tt.wantErr)
 
 			if value != tt.expected {
 				t.Errorf("Function() = %d; want %d", value, tt.expected)
@@ -186,7 +182,7 @@ func TestGetEnvAsStringSlice(t *testing.T) {
 		required bool
 		fallback []string
 		expected []string
-		wantExit bool
+		wantErr  error
 	}{
 		{
 			name:     "Required",
@@ -195,7 +191,6 @@ func TestGetEnvAsStringSlice(t *testing.T) {
 			required: true,
 			fallback: []string{},
 			expected: []string{"test1", "test2", "test3"},
-			wantExit: false,
 		},
 		{
 			name:     "RequiredMissing",
@@ -204,7 +199,16 @@ func TestGetEnvAsStringSlice(t *testing.T) {
 			required: true,
 			fallback: []string{},
 			expected: []string{},
-			wantExit: true,
+			wantErr:  ErrRequired,
+		},
+		{
+			name:     "RequiredOnlySeparators",
+			key:      testKey,
+			value:    " , ,",
+			required: true,
+			fallback: []string{},
+			expected: []string{},
+			wantErr:  ErrRequired,
 		},
 		{
 			name:     "NotRequired",
@@ -213,7 +217,6 @@ func TestGetEnvAsStringSlice(t *testing.T) {
 			required: false,
 			fallback: []string{},
 			expected: []string{"test1", "test2", "test3"},
-			wantExit: false,
 		},
 		{
 			name:     "NotRequiredWithFallback",
@@ -222,34 +225,308 @@ func TestGetEnvAsStringSlice(t *testing.T) {
 			required: false,
 			fallback: []string{"test1", "test2", "test3"},
 			expected: []string{"test1", "test2", "test3"},
-			wantExit: false,
 		},
-		// Add more test cases here
+		{
+			name:     "Trimmed",
+			key:      testKey,
+			value:    " test1 ,, test2,test3 , ",
+			required: false,
+			fallback: []string{},
+			expected: []string{"test1", "test2", "test3"},
+		},
 	}
 
 	for _, tt := range tests {
 		setup(tt.value)
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.wantExit {
-				if os.Getenv("BE_CRASHER") == "1" {
-					GetEnvAsStringSlice(tt.key, tt.required, tt.fallback)
-					return
-				}
-				cmd := exec.Command(os.Args[0], "-test.run=TestGetEnv")
-				cmd.Env = append(os.Environ(), "BE_CRASHER=1")
-				err := cmd.Run()
-				if e, ok := err.(*exec.ExitError); ok && !e.Success() {
-					return
-				}
-				t.Fatalf("process ran with err %v, want exit status 1", err)
+			value, err := GetEnvAsStringSlice(tt.key, tt.required, tt.fallback)
+			checkErr(t, err, tt.wantErr)
+
+			if !reflect.DeepEqual(value, tt.expected) {
+				t.Errorf("Function() = %q; want %q", value, tt.expected)
 			}
+		})
+		teardown()
+	}
+}
+
+func TestGetEnvAsBool(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		required bool
+		fallback bool
+		expected bool
+		wantErr  error
+	}{
+		{
+			name:     "Required",
+			key:      testKey,
+			value:    "true",
+			required: true,
+			fallback: false,
+			expected: true,
+		},
+		{
+			name:     "RequiredMissing",
+			key:      testKey,
+			value:    "",
+			required: true,
+			fallback: false,
+			expected: false,
+			wantErr:  ErrRequired,
+		},
+		{
+			name:     "NotRequired",
+			key:      testKey,
+			value:    "false",
+			required: false,
+			fallback: true,
+			expected: false,
+		},
+		{
+			name:     "NotRequiredWithFallback",
+			key:      testKey,
+			value:    "",
+			required: false,
+			fallback: true,
+			expected: true,
+		},
+		{
+			name:     "Invalid",
+			key:      testKey,
+			value:    "maybe",
+			required: false,
+			fallback: true,
+			expected: true,
+			wantErr:  ErrInvalid,
+		},
+	}
 
-			value := GetEnvAsStringSlice(tt.key, tt.required, tt.fallback)
+	for _, tt := range tests {
+		setup(tt.value)
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := GetEnvAsBool(tt.key, tt.required, tt.fallback)
+			checkErr(t, err, tt.wantErr)
 
-			if len(value) != len(tt.expected) {
+			if value != tt.expected {
+				t.Errorf("Function() = %t; want %t", value, tt.expected)
+			}
+		})
+		teardown()
+	}
+}
+
+func TestGetEnvAsDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		required bool
+		fallback time.Duration
+		expected time.Duration
+		wantErr  error
+	}{
+		{
+			name:     "Seconds",
+			key:      testKey,
+			value:    "300",
+			required: true,
+			fallback: 0,
+			expected: 5 * time.Minute,
+		},
+		{
+			name:     "Duration",
+			key:      testKey,
+			value:    "1h30m",
+			required: true,
+			fallback: 0,
+			expected: 90 * time.Minute,
+		},
+		{
+			name:     "RequiredMissing",
+			key:      testKey,
+			value:    "",
+			required: true,
+			fallback: 0,
+			expected: 0,
+			wantErr:  ErrRequired,
+		},
+		{
+			name:     "NotRequiredWithFallback",
+			key:      testKey,
+			value:    "",
+			required: false,
+			fallback: time.Hour,
+			expected: time.Hour,
+		},
+		{
+			name:     "Invalid",
+			key:      testKey,
+			value:    "daily",
+			required: false,
+			fallback: time.Hour,
+			expected: time.Hour,
+			wantErr:  ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		setup(tt.value)
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := GetEnvAsDuration(tt.key, tt.required, tt.fallback)
+			checkErr(t, err, tt.wantErr)
+
+			if value != tt.expected {
 				t.Errorf("Function() = %v; want %v", value, tt.expected)
 			}
 		})
 		teardown()
 	}
 }
+
+func TestGetEnvAsURL(t *testing.T) {
+	fallback := &url.URL{Scheme: "https", Host: "example.com"}
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		required bool
+		fallback *url.URL
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "Required",
+			key:      testKey,
+			value:    "https://ip.example.com/raw?format=text",
+			required: true,
+			expected: "https://ip.example.com/raw?format=text",
+		},
+		{
+			name:     "RequiredMissing",
+			key:      testKey,
+			value:    "",
+			required: true,
+			expected: "",
+			wantErr:  ErrRequired,
+		},
+		{
+			name:     "NotRequiredWithFallback",
+			key:      testKey,
+			value:    "",
+			required: false,
+			fallback: fallback,
+			expected: "https://example.com",
+		},
+		{
+			name:     "Relative",
+			key:      testKey,
+			value:    "ip.example.com/raw",
+			required: false,
+			fallback: fallback,
+			expected: "https://example.com",
+			wantErr:  ErrInvalid,
+		},
+		{
+			name:     "Malformed",
+			key:      testKey,
+			value:    "https://%zz",
+			required: false,
+			expected: "",
+			wantErr:  ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		setup(tt.value)
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := GetEnvAsURL(tt.key, tt.required, tt.fallback)
+			checkErr(t, err, tt.wantErr)
+
+			got := ""
+			if value != nil {
+				got = value.String()
+			}
+			if got != tt.expected {
+				t.Errorf("Function() = %s; want %s", got, tt.expected)
+			}
+		})
+		teardown()
+	}
+}
+
+func TestGetEnv_File(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("supersecret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		file     string
+		required bool
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "File",
+			file:     secret,
+			required: true,
+			expected: "supersecret",
+		},
+		{
+			name:     "Value",
+			value:    testString,
+			required: true,
+			expected: testString,
+		},
+		{
+			name:     "Both",
+			value:    testString,
+			file:     secret,
+			required: true,
+			expected: "",
+			wantErr:  ErrConflict,
+		},
+		{
+			name:     "MissingFile",
+			file:     filepath.Join(t.TempDir(), "missing"),
+			required: false,
+			expected: "",
+			wantErr:  fs.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(testKey, tt.value)
+			t.Setenv(testKey+FileSuffix, tt.file)
+
+			value, err := GetEnv(testKey, tt.required, "")
+			checkErr(t, err, tt.wantErr)
+
+			if value != tt.expected {
+				t.Errorf("Function() = %s; want %s", value, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvAsStringSlice_File(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "zones")
+	if err := os.WriteFile(list, []byte("example.com,example.org\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testKey+FileSuffix, list)
+
+	value, err := GetEnvAsStringSlice(testKey, true, nil)
+	checkErr(t, err, nil)
+
+	if !reflect.DeepEqual(value, []string{"example.com", "example.org"}) {
+		t.Errorf("Function() = %q; want [example.com example.org]", value)
+	}
+}
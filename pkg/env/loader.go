@@ -0,0 +1,80 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Loader reads several environment variables, collecting their errors
+// so that every problem can be reported at once
+type Loader struct {
+	errs []error
+}
+
+// NewLoader creates a new Loader
+func NewLoader() *Loader {
+	return &Loader{}
+}
+
+// String returns the value of the environment variable, see GetEnv
+func (l *Loader) String(name string, required bool, fallback string) string {
+	value, err := GetEnv(name, required, fallback)
+	l.Add(err)
+	return value
+}
+
+// Int returns the value of the environment variable as an integer, see GetEnvAsInt
+func (l *Loader) Int(name string, required bool, fallback int) int {
+	value, err := GetEnvAsInt(name, required, fallback)
+	l.Add(err)
+	return value
+}
+
+// Bool returns the value of the environment variable as a boolean, see GetEnvAsBool
+func (l *Loader) Bool(name string, required bool, fallback bool) bool {
+	value, err := GetEnvAsBool(name, required, fallback)
+	l.Add(err)
+	return value
+}
+
+// Duration returns the value of the environment variable as a duration, see GetEnvAsDuration
+func (l *Loader) Duration(name string, required bool, fallback time.Duration) time.Duration {
+	value, err := GetEnvAsDuration(name, required, fallback)
+	l.Add(err)
+	return value
+}
+
+// URL returns the value of the environment variable as an absolute URL, see GetEnvAsURL
+func (l *Loader) URL(name string, required bool, fallback *url.URL) *url.URL {
+	value, err := GetEnvAsURL(name, required, fallback)
+	l.Add(err)
+	return value
+}
+
+// StringSlice returns the value of the environment variable as a string slice, see GetEnvAsStringSlice
+func (l *Loader) StringSlice(name string, required bool, fallback []string) []string {
+	value, err := GetEnvAsStringSlice(name, required, fallback)
+	l.Add(err)
+	return value
+}
+
+// Add collects an error, ignoring nil ones
+func (l *Loader) Add(err error) {
+	if err != nil {
+		l.errs = append(l.errs, err)
+	}
+}
+
+// Check collects an error with the given message when the condition is false
+func (l *Loader) Check(condition bool, format string, args ...interface{}) {
+	if !condition {
+		l.errs = append(l.errs, fmt.Errorf(format, args...))
+	}
+}
+
+// Err returns all the collected errors joined together, or nil if there are none
+func (l *Loader) Err() error {
+	return errors.Join(l.errs...)
+}
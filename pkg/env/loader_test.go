@@ -0,0 +1,63 @@
+package env
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoader(t *testing.T) {
+	t.Setenv("TEST_INT", "ten")
+	t.Setenv("TEST_BOOL", "yes please")
+	t.Setenv("TEST_DURATION", "5m")
+	t.Setenv("TEST_SLICE", "a, b")
+
+	l := NewLoader()
+	if got := l.Int("TEST_INT", false, 10); got != 10 {
+		t.Errorf("Int() = %d; want the fallback 10", got)
+	}
+	if got := l.Bool("TEST_BOOL", false, true); !got {
+		t.Errorf("Bool() = %t; want the fallback true", got)
+	}
+	if got := l.Duration("TEST_DURATION", false, 0); got != 5*time.Minute {
+		t.Errorf("Duration() = %v; want 5m", got)
+	}
+	if got := l.StringSlice("TEST_SLICE", false, nil); len(got) != 2 {
+		t.Errorf("StringSlice() = %q; want 2 items", got)
+	}
+	if got := l.String("TEST_MISSING", true, ""); got != "" {
+		t.Errorf("String() = %s; want an empty string", got)
+	}
+	l.Check(false, "value %d is out of range", 42)
+	l.Check(true, "never reported")
+
+	err := l.Err()
+	if err == nil {
+		t.Fatal("Err() = nil; want the collected errors")
+	}
+
+	for _, want := range []string{"TEST_INT", "TEST_BOOL", "TEST_MISSING", "value 42 is out of range"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Err() = %v; want it to mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "never reported") {
+		t.Errorf("Err() = %v; want passing checks to be ignored", err)
+	}
+	if !errors.Is(err, ErrInvalid) || !errors.Is(err, ErrRequired) {
+		t.Errorf("Err() = %v; want it to wrap ErrInvalid and ErrRequired", err)
+	}
+}
+
+func TestLoader_NoErrors(t *testing.T) {
+	t.Setenv("TEST_STRING", "value")
+
+	l := NewLoader()
+	l.String("TEST_STRING", true, "")
+	l.Add(nil)
+
+	if err := l.Err(); err != nil {
+		t.Errorf("Err() = %v; want nil", err)
+	}
+}
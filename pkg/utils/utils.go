@@ -9,3 +9,21 @@ func StringInSlice(a string, list []string) bool {
 	}
 	return false
 }
+
+// SameStrings checks if two slices hold the same strings, in any order
+func SameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		if counts[s] == 0 {
+			return false
+		}
+		counts[s]--
+	}
+	return true
+}